@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+
+	"github.com/aerogear/ups-config-operator/pkg/configOperator"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+const (
+	logLevelInfo  = "info"
+	logLevelDebug = "debug"
+
+	// Namespace of the pod when running in a cluster
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Settings of the operator. Every flag defaults to an environment variable, so deployments
+// that are configured with environment variables keep working.
+type config struct {
+	kubeconfig    string
+	namespace     string
+	upsSecretName string
+	logLevel      string
+	metricsAddr   string
+	dryRun        bool
+
+	orphanedVariantPolicy configOperator.OrphanedVariantPolicy
+	driftMissedPolls      int
+	driftMaxDeletions     int
+
+	pollingInterval     time.Duration
+	upsRequestTimeout   time.Duration
+	kubeRequestTimeout  time.Duration
+	shutdownGracePeriod time.Duration
+	livenessWindow      time.Duration
+	leaseDuration       time.Duration
+	renewDeadline       time.Duration
+	retryPeriod         time.Duration
+}
+
+// Parses the command line. The flags of the Kubernetes client libraries, e.g. `-v`, are
+// registered on the same flag set and parsed as well.
+func parseConfig() (*config, error) {
+	cfg := new(config)
+
+	flag.StringVar(&cfg.kubeconfig, "kubeconfig", os.Getenv(constants.EnvVarKeyKubeconfig), "Path to a kubeconfig file, to run outside of a cluster. Uses the in-cluster config when empty")
+	flag.StringVar(&cfg.namespace, "namespace", os.Getenv(constants.EnvVarKeyNamespace), "Namespace to watch. Defaults to the namespace of the pod or of the kubeconfig context")
+	flag.StringVar(&cfg.upsSecretName, "ups-secret-name", stringFromEnv(constants.EnvVarKeyUpsSecretName, constants.UpsSecretName), "Name of the secret with the URL and credentials of UPS")
+	flag.StringVar(&cfg.logLevel, "log-level", stringFromEnv(constants.EnvVarKeyLogLevel, logLevelInfo), "Either info or debug. Debug also shows the logs of the Kubernetes client")
+	flag.StringVar(&cfg.metricsAddr, "metrics-address", stringFromEnv(constants.EnvVarKeyMetricsAddress, constants.MetricsAddress), "Address to serve the /metrics, /healthz and /readyz endpoints on. Disabled when empty")
+	orphanedVariantPolicy := flag.String("orphaned-variant-policy", stringFromEnv(constants.EnvVarKeyOrphanedVariantPolicy, string(configOperator.OrphanedVariantPolicyReport)), "What to do with variants in UPS that have no config secret: report, adopt or delete")
+	flag.IntVar(&cfg.driftMissedPolls, "drift-missed-polls", intFromEnv(constants.EnvVarKeyDriftMissedPolls, constants.DriftMissedPolls), "Number of polls in a row a variant that cannot be looked up has to be missing from UPS in before its service binding is deleted")
+	flag.IntVar(&cfg.driftMaxDeletions, "drift-max-deletions", intFromEnv(constants.EnvVarKeyDriftMaxDeletions, constants.DriftMaxDeletions), "Maximum number of service bindings a poll deletes because their variants are missing from UPS. 0 for no limit")
+	flag.BoolVar(&cfg.dryRun, "dry-run", boolFromEnv(constants.EnvVarKeyDryRun, false), "Log the changes to UPS and Kubernetes instead of making them")
+
+	flag.DurationVar(&cfg.pollingInterval, "ups-polling-interval", durationFromEnv(constants.EnvVarKeyUpsPollingInterval, constants.UPSPollingInterval*time.Second), "Interval in which UPS is checked for deleted variants")
+	flag.DurationVar(&cfg.upsRequestTimeout, "ups-request-timeout", durationFromEnv(constants.EnvVarKeyUpsRequestTimeout, constants.UPSRequestTimeout*time.Second), "Timeout of a single request to UPS")
+	flag.DurationVar(&cfg.kubeRequestTimeout, "kube-request-timeout", durationFromEnv(constants.EnvVarKeyKubeRequestTimeout, constants.KubeRequestTimeout*time.Second), "Timeout of a single request to the Kubernetes API")
+	flag.DurationVar(&cfg.shutdownGracePeriod, "shutdown-grace-period", durationFromEnv(constants.EnvVarKeyShutdownGracePeriod, constants.ShutdownGracePeriod*time.Second), "Time that in-flight work may take after a termination signal")
+	flag.DurationVar(&cfg.livenessWindow, "liveness-window", durationFromEnv(constants.EnvVarKeyLivenessWindow, constants.LivenessWindow*time.Second), "Time in which the secret watch and the UPS poll have to make progress to pass the liveness check")
+	flag.DurationVar(&cfg.leaseDuration, "leader-election-lease-duration", durationFromEnv(constants.EnvVarKeyLeaderElectionLeaseDuration, constants.LeaderElectionLeaseDuration*time.Second), "Time after which a standby replica takes over from a leader that stopped renewing its lock")
+	flag.DurationVar(&cfg.renewDeadline, "leader-election-renew-deadline", durationFromEnv(constants.EnvVarKeyLeaderElectionRenewDeadline, constants.LeaderElectionRenewDeadline*time.Second), "Time the leader keeps retrying to renew its lock before it exits")
+	flag.DurationVar(&cfg.retryPeriod, "leader-election-retry-period", durationFromEnv(constants.EnvVarKeyLeaderElectionRetryPeriod, constants.LeaderElectionRetryPeriod*time.Second), "Interval between attempts to acquire or renew the lock")
+
+	flag.Parse()
+
+	if cfg.logLevel != logLevelInfo && cfg.logLevel != logLevelDebug {
+		return nil, errors.Errorf("invalid log level `%s`, expected `%s` or `%s`", cfg.logLevel, logLevelInfo, logLevelDebug)
+	}
+	policy, err := configOperator.ParseOrphanedVariantPolicy(*orphanedVariantPolicy)
+	if err != nil {
+		return nil, err
+	}
+	cfg.orphanedVariantPolicy = policy
+	if cfg.driftMissedPolls < 1 {
+		return nil, errors.New("the number of missed polls must be at least 1")
+	}
+	if cfg.driftMaxDeletions < 0 {
+		return nil, errors.New("the maximum number of deletions must not be negative")
+	}
+	if cfg.upsSecretName == "" {
+		return nil, errors.New("the UPS secret name must not be empty")
+	}
+	if cfg.pollingInterval <= 0 {
+		return nil, errors.New("the UPS polling interval must be positive")
+	}
+	if cfg.livenessWindow <= cfg.pollingInterval {
+		return nil, errors.New("the liveness window must be longer than the UPS polling interval")
+	}
+	// The leader election panics on other values. The retry period is jittered, so the renew
+	// deadline has to leave room for the longest retry.
+	if cfg.retryPeriod <= 0 {
+		return nil, errors.New("the leader election retry period must be positive")
+	}
+	if maxRetryPeriod := time.Duration(leaderelection.JitterFactor * float64(cfg.retryPeriod)); cfg.renewDeadline <= maxRetryPeriod {
+		return nil, errors.Errorf("the leader election renew deadline (%s) must be longer than %.1f times the retry period (%s)", cfg.renewDeadline, leaderelection.JitterFactor, cfg.retryPeriod)
+	}
+	if cfg.leaseDuration <= cfg.renewDeadline {
+		return nil, errors.Errorf("the leader election lease duration (%s) must be longer than the renew deadline (%s)", cfg.leaseDuration, cfg.renewDeadline)
+	}
+
+	return cfg, nil
+}
+
+func (cfg *config) applyLogLevel() {
+	if cfg.logLevel == logLevelDebug {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+		flag.Set("logtostderr", "true")
+		flag.Set("v", "4")
+	}
+}
+
+// Returns the client config from the kubeconfig file when one is given, the in-cluster config
+// otherwise. An empty namespace is set to the namespace of the kubeconfig context or of the pod.
+func (cfg *config) loadKubeConfig() (*rest.Config, error) {
+	if cfg.kubeconfig == "" {
+		kubeConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load the in-cluster config, use -kubeconfig to run outside of a cluster")
+		}
+		if cfg.namespace == "" {
+			namespace, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot determine the namespace of the pod, please set -namespace")
+			}
+			cfg.namespace = strings.TrimSpace(string(namespace))
+		}
+		return kubeConfig, nil
+	}
+
+	// KUBECONFIG may contain a list of files like kubectl supports it
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(cfg.kubeconfig)}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	kubeConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load kubeconfig %s", cfg.kubeconfig)
+	}
+	if cfg.namespace == "" {
+		cfg.namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot determine the namespace of kubeconfig %s", cfg.kubeconfig)
+		}
+	}
+	return kubeConfig, nil
+}
+
+func stringFromEnv(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func boolFromEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean `%s` in %s, using the default of %t", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return result
+}
+
+func intFromEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid number `%s` in %s, using the default of %d", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return result
+}
+
+// Reads a duration like `10s` from an environment variable
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration `%s` in %s, using the default of %s", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return duration
+}
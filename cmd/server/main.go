@@ -1,44 +1,200 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 
 	"math/rand"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
 	sc "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
 
+	push "github.com/aerogear/ups-config-operator/pkg/client/push/clientset/versioned"
 	"github.com/aerogear/ups-config-operator/pkg/configOperator"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
 )
 
 func main() {
 	rand.Seed(time.Now().Unix())
 
-	config, err := rest.InClusterConfig()
+	cfg, err := parseConfig()
 	if err != nil {
-		panic(err.Error())
+		log.Fatalf("Invalid configuration: %s", err.Error())
+	}
+	cfg.applyLogLevel()
+
+	config, err := cfg.loadKubeConfig()
+	if err != nil {
+		log.Fatal(err.Error())
 	}
+	log.Printf("Watching namespace %s", cfg.namespace)
+
+	// Watches are long running requests and need clients without a timeout
+	watchclient := kubernetes.NewForConfigOrDie(rest.CopyConfig(config))
+	scwatchclient := sc.NewForConfigOrDie(rest.CopyConfig(config))
+	pushwatchclient := push.NewForConfigOrDie(rest.CopyConfig(config))
+	mobilewatchclient := mc.NewForConfigOrDie(rest.CopyConfig(config))
+
+	config.Timeout = cfg.kubeRequestTimeout
 
 	k8client := kubernetes.NewForConfigOrDie(config)
 	scclient := sc.NewForConfigOrDie(config)
 	mobileclient := mc.NewForConfigOrDie(config)
-	pushClientProvider := configOperator.NewUpsClientProviderImpl(k8client)
+	pushclient := push.NewForConfigOrDie(config)
+	pushClientProvider := configOperator.NewUpsClientProviderImpl(k8client, pushclient, cfg.namespace, cfg.upsSecretName, cfg.upsRequestTimeout)
 
-	if err != nil {
-		log.Printf("error initialising UPS client: %s", err.Error())
+	platforms := configOperator.NewDefaultPlatformRegistry()
+
+	annotationHelper := configOperator.NewAnnotationHelper(mobileclient, platforms, cfg.namespace)
+
+	kubeHelper := configOperator.NewKubeHelper(k8client, watchclient, scclient, scwatchclient, pushclient, pushwatchclient, mobileclient, mobilewatchclient, cfg.namespace)
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8client.CoreV1().Events(cfg.namespace)})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: constants.EventSourceComponent})
+
+	eventHelper := configOperator.NewEventHelper(recorder, mobileclient, scclient, cfg.namespace)
+
+	// In dry-run mode reads go to UPS and Kubernetes, changes are only recorded
+	var dryRunRecorder *configOperator.DryRunRecorder
+	var operatorPushClientProvider configOperator.UpsClientProvider = pushClientProvider
+	var operatorAnnotationHelper configOperator.AnnotationHelper = annotationHelper
+	var operatorKubeHelper configOperator.KubeHelper = kubeHelper
+	var operatorEventHelper configOperator.EventHelper = eventHelper
+	if cfg.dryRun {
+		log.Print("Dry-run mode, changes to UPS and Kubernetes are logged instead of made")
+		dryRunRecorder = configOperator.NewDryRunRecorder(constants.DryRunActionsLimit)
+		operatorPushClientProvider = configOperator.NewDryRunUpsClientProvider(pushClientProvider, dryRunRecorder)
+		operatorAnnotationHelper = configOperator.NewDryRunAnnotationHelper(dryRunRecorder)
+		operatorKubeHelper = configOperator.NewDryRunKubeHelper(kubeHelper, dryRunRecorder)
+		operatorEventHelper = configOperator.NewDryRunEventHelper(dryRunRecorder)
+	}
+
+	operator := configOperator.NewConfigOperator(operatorPushClientProvider, operatorAnnotationHelper, operatorKubeHelper, operatorEventHelper, platforms, cfg.pollingInterval, configOperator.NewHealth(cfg.livenessWindow), cfg.driftMissedPolls, cfg.driftMaxDeletions, cfg.orphanedVariantPolicy)
+
+	if cfg.metricsAddr != "" {
+		go serveHTTP(cfg.metricsAddr, operator, dryRunRecorder)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, shutting down", sig)
+		cancel()
+	}()
+
+	// A dry run doesn't take the lock, so that it can run alongside the operator it is compared to
+	if cfg.dryRun {
+		operator.StartService(ctx, cfg.shutdownGracePeriod)
+		log.Print("Exiting")
 		return
 	}
 
-	annotationHelper := configOperator.NewAnnotationHelper(mobileclient)
+	// This is blocking until a termination signal is received and the operator has stopped
+	runAsLeader(ctx, cfg, k8client, recorder, func(ctx context.Context) {
+		operator.StartService(ctx, cfg.shutdownGracePeriod)
+	})
+	log.Print("Exiting")
+}
+
+// Serves the Prometheus metrics and the health checks on all replicas. Standby replicas only
+// report the metrics of the Go runtime and the process and are always live and ready.
+// In dry-run mode the recorded changes are served on `/dry-run`.
+func serveHTTP(addr string, operator *configOperator.ConfigOperator, dryRunRecorder *configOperator.DryRunRecorder) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", operator.ServeHealthz)
+	mux.HandleFunc("/readyz", operator.ServeReadyz)
+	if dryRunRecorder != nil {
+		mux.Handle("/dry-run", dryRunRecorder)
+	}
+
+	log.Printf("Serving metrics and health checks on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// Runs the operator only while this replica holds the leader election lock, so that several
+// replicas don't handle the same binding secrets. Exits when the lock is lost: the informers
+// and polling loop of the old leader might still be busy and a restart is the safest way
+// to stop them before the new leader starts.
+// Returns once ctx is done and run has returned. The lock is not released, a standby replica
+// takes over when the lease expires.
+func runAsLeader(ctx context.Context, cfg *config, k8client *kubernetes.Clientset, recorder record.EventRecorder, run func(ctx context.Context)) {
+	namespace := cfg.namespace
+
+	identity, err := os.Hostname()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock, namespace, constants.LeaderElectionLockName, k8client.CoreV1(), resourcelock.ResourceLockConfig{
+		Identity:      identity,
+		EventRecorder: recorder,
+	})
+	if err != nil {
+		panic(err.Error())
+	}
+
+	log.Printf("Waiting to become the leader as `%s`", identity)
 
-	kubeHelper := configOperator.NewKubeHelper(k8client, scclient)
+	leading := make(chan struct{})
+	finished := make(chan struct{})
 
-	operator := configOperator.NewConfigOperator(pushClientProvider, annotationHelper, kubeHelper)
+	// RunOrDie doesn't return while the lock is held, it runs until the process exits
+	go leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: cfg.leaseDuration,
+		RenewDeadline: cfg.renewDeadline,
+		RetryPeriod:   cfg.retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(stop <-chan struct{}) {
+				defer close(finished)
+				close(leading)
+				if ctx.Err() != nil {
+					return
+				}
+				log.Print("Became the leader, starting the operator")
 
-	// This is blocking. Any code after this will not be called
-	operator.StartService()
+				leaderCtx, cancel := context.WithCancel(ctx)
+				go func() {
+					select {
+					case <-stop:
+					case <-leaderCtx.Done():
+					}
+					cancel()
+				}()
+				run(leaderCtx)
+			},
+			OnStoppedLeading: func() {
+				log.Fatal("Lost the leader election lock, exiting")
+			},
+		},
+	})
+
+	<-ctx.Done()
+	select {
+	case <-leading:
+		<-finished
+	default:
+	}
 }
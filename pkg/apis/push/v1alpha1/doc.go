@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package,register
+
+// Package v1alpha1 defines the custom resources that describe the desired state of UPS.
+// +groupName=push.aerogear.org
+package v1alpha1
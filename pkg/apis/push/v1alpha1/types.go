@@ -0,0 +1,126 @@
+package v1alpha1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// A variant in UPS. The operator creates, updates and deletes the variant so that it matches
+// the spec, and writes the variant config into the config secret of the mobile client.
+type PushVariant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              PushVariantSpec   `json:"spec"`
+	Status            PushVariantStatus `json:"status,omitempty"`
+}
+
+type PushVariantSpec struct {
+	// Name of the platform, e.g. `android`, `ios` or `webpush`
+	Platform string `json:"platform"`
+	// Name of the MobileClient the variant belongs to
+	ClientId string `json:"clientId"`
+	// Secret with the credentials of the platform. It uses the same keys as the binding
+	// secrets, e.g. `googleKey` and `projectNumber` for Android.
+	SecretRef v1.LocalObjectReference `json:"secretRef"`
+	// Whether iOS variants use the production APNs environment
+	Production bool `json:"production,omitempty"`
+	// ExternalID of the service binding the variant has been created for, if any
+	ServiceBindingId string `json:"serviceBindingId,omitempty"`
+	// Name of the UPS service instance, used to name the annotations of the mobile client
+	ServiceInstanceName string `json:"serviceInstanceName,omitempty"`
+}
+
+type PushVariantConditionType string
+
+const (
+	// The variant exists in UPS and matches the spec
+	PushVariantReady PushVariantConditionType = "Ready"
+)
+
+type PushVariantCondition struct {
+	Type               PushVariantConditionType `json:"type"`
+	Status             v1.ConditionStatus       `json:"status"`
+	LastTransitionTime metav1.Time              `json:"lastTransitionTime,omitempty"`
+	Reason             string                   `json:"reason,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+}
+
+type PushVariantStatus struct {
+	Conditions []PushVariantCondition `json:"conditions,omitempty"`
+	// ID of the variant in UPS
+	VariantId string `json:"variantId,omitempty"`
+	// Name of the secret the variant config has been written to
+	ConfigSecretName string `json:"configSecretName,omitempty"`
+	// Error of the last attempt to bring UPS in line with the spec, empty once it succeeded
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type PushVariantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PushVariant `json:"items"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// A push application in UPS. The operator creates the application if it doesn't exist yet,
+// keeps its name and description in line with the spec and deletes it together with the resource.
+type PushApplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              PushApplicationSpec   `json:"spec"`
+	Status            PushApplicationStatus `json:"status,omitempty"`
+}
+
+type PushApplicationSpec struct {
+	// Name of the application in UPS, defaults to the name of the resource
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// ID of an existing application in UPS to use instead of creating one. The operator never
+	// deletes applications it didn't create.
+	ApplicationId string `json:"applicationId,omitempty"`
+}
+
+type PushApplicationConditionType string
+
+const (
+	// The application exists in UPS and matches the spec
+	PushApplicationReady PushApplicationConditionType = "Ready"
+)
+
+type PushApplicationCondition struct {
+	Type               PushApplicationConditionType `json:"type"`
+	Status             v1.ConditionStatus           `json:"status"`
+	LastTransitionTime metav1.Time                  `json:"lastTransitionTime,omitempty"`
+	Reason             string                       `json:"reason,omitempty"`
+	Message            string                       `json:"message,omitempty"`
+}
+
+type PushApplicationStatus struct {
+	Conditions []PushApplicationCondition `json:"conditions,omitempty"`
+	// ID of the application in UPS
+	ApplicationId string `json:"applicationId,omitempty"`
+	// Whether the operator created the application, only those are deleted with the
+	// PushApplication
+	CreatedByOperator bool `json:"createdByOperator,omitempty"`
+	// Name of the secret that holds the application ID and the master secret
+	SecretName string `json:"secretName,omitempty"`
+	// Error of the last attempt to bring UPS in line with the spec, empty once it succeeded
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type PushApplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PushApplication `json:"items"`
+}
@@ -0,0 +1,57 @@
+package configOperator
+
+import (
+	"context"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Android variants use Firebase Cloud Messaging
+type AndroidPlatform struct{}
+
+func (platform *AndroidPlatform) getName() string {
+	return "android"
+}
+
+func (platform *AndroidPlatform) getLabel() string {
+	return "Android"
+}
+
+func (platform *AndroidPlatform) parseBinding(secret *BindingSecret) (PlatformVariant, error) {
+	return &AndroidVariant{
+		ProjectNumber: string(secret.Data[constants.BindingDataProjectNumberKey]),
+		GoogleKey:     string(secret.Data[constants.BindingDataGoogleKey]),
+	}, nil
+}
+
+func (platform *AndroidPlatform) createVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	created, err := pushClient.createAndroidVariant(ctx, variant.(*AndroidVariant))
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (platform *AndroidPlatform) findVariant(ctx context.Context, pushClient UpsClient, name string) (*Variant, error) {
+	return findVariantByName(ctx, pushClient, name, "android")
+}
+
+func (platform *AndroidPlatform) listVariants(ctx context.Context, pushClient UpsClient) ([]Variant, error) {
+	return listVariantsOf(ctx, pushClient, "android")
+}
+
+func (platform *AndroidPlatform) getVariant(ctx context.Context, pushClient UpsClient, variantId string) (*Variant, error) {
+	return getVariantOf(ctx, pushClient, variantId, "android")
+}
+
+func (platform *AndroidPlatform) updateVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	updated, err := pushClient.updateAndroidVariant(ctx, variant.(*AndroidVariant))
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (platform *AndroidPlatform) deleteVariant(ctx context.Context, pushClient UpsClient, secret *BindingSecret, variantId string) error {
+	return pushClient.deleteVariant(ctx, "android", variantId)
+}
@@ -1,39 +1,44 @@
 package configOperator
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"log"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
 	"github.com/aerogear/ups-config-operator/pkg/constants"
 	"encoding/json"
-	"strings"
 )
 
 type AnnotationHelper interface {
-	addAnnotationToMobileClient(clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantUrl string, serviceInstanceName string)
-	removeAnnotationFromMobileClient(clientId string, appType string, serviceInstanceName string)
+	addAnnotationToMobileClient(ctx context.Context, clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantUrl string, serviceInstanceName string)
+	removeAnnotationFromMobileClient(ctx context.Context, clientId string, appType string, serviceInstanceName string)
+	// Adds or replaces the push entry in the status of the mobile client, which mobile-services.json is generated from
+	setPushServiceStatus(ctx context.Context, clientId string, service v1alpha1.MobileClientService)
+	removePushServiceStatus(ctx context.Context, clientId string)
 }
 
 type AnnotationHelperImpl struct {
 	mobileclient *mc.Clientset
+	platforms    *PlatformRegistry
+	namespace    string
 }
 
-func NewAnnotationHelper(mobileclient *mc.Clientset) *AnnotationHelperImpl {
+func NewAnnotationHelper(mobileclient *mc.Clientset, platforms *PlatformRegistry, namespace string) *AnnotationHelperImpl {
 	helper := new(AnnotationHelperImpl)
 
 	helper.mobileclient = mobileclient
+	helper.platforms = platforms
+	helper.namespace = namespace
 
 	return helper
 }
 
 // Adds an annotation to the mobile client that contains information about this variant
 // (currently URL and Name)
-func (helper AnnotationHelperImpl) addAnnotationToMobileClient(clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantId string, serviceInstanceName string) {
-	client, err := helper.mobileclient.MobileV1alpha1().MobileClients(os.Getenv(constants.EnvVarKeyNamespace)).Get(clientId, metav1.GetOptions{})
+func (helper AnnotationHelperImpl) addAnnotationToMobileClient(ctx context.Context, clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantId string, serviceInstanceName string) {
+	client, err := helper.getMobileClient(ctx, clientId)
 	if err != nil {
 		log.Printf("No mobile client with name %s found", clientId)
 		return
@@ -51,7 +56,7 @@ func (helper AnnotationHelperImpl) addAnnotationToMobileClient(clientId string,
 	extVariantAnnotationName := fmt.Sprintf(constants.ExtVariantsAnnotationNameFormat, serviceInstanceName)
 	extVariantAnnotationConfigForSingleVariant := variantAnnotationConfig{
 		Type:      appType,
-		TypeLabel: getVariantTypeLabel(appType),
+		TypeLabel: helper.platforms.getLabel(appType),
 		Url:       variantUrl,
 		Id:        variantId,
 	}
@@ -95,14 +100,14 @@ func (helper AnnotationHelperImpl) addAnnotationToMobileClient(clientId string,
 
 	client.Annotations[extVariantAnnotationName] = string(extVariantAnnotationConfigValueStr)
 
-	_, err = helper.mobileclient.MobileV1alpha1().MobileClients(os.Getenv(constants.EnvVarKeyNamespace)).Update(client)
+	err = helper.updateMobileClient(ctx, client)
 	if err != nil {
-		log.Printf(err.Error())
+		log.Print(err.Error())
 	}
 }
 
-func (helper AnnotationHelperImpl) removeAnnotationFromMobileClient(clientId string, appType string, serviceInstanceName string) {
-	client, err := helper.mobileclient.MobileV1alpha1().MobileClients(os.Getenv(constants.EnvVarKeyNamespace)).Get(clientId, metav1.GetOptions{})
+func (helper AnnotationHelperImpl) removeAnnotationFromMobileClient(ctx context.Context, clientId string, appType string, serviceInstanceName string) {
+	client, err := helper.getMobileClient(ctx, clientId)
 	if err != nil {
 		log.Printf("No mobile client with name %s found", clientId)
 		return
@@ -143,7 +148,7 @@ func (helper AnnotationHelperImpl) removeAnnotationFromMobileClient(clientId str
 
 		client.Annotations[extVariantAnnotationName] = string(newConfigStr)
 
-		_, err = helper.mobileclient.MobileV1alpha1().MobileClients(os.Getenv(constants.EnvVarKeyNamespace)).Update(client)
+		err = helper.updateMobileClient(ctx, client)
 		if err != nil {
 			log.Printf("Unable to update mobile client %s. Error: %s", clientId, err.Error())
 		}
@@ -158,7 +163,7 @@ func (helper AnnotationHelperImpl) removeAnnotationFromMobileClient(clientId str
 		delete(client.Annotations, upsUrlAnnotationName)
 		delete(client.Annotations, extVariantAnnotationName)
 
-		_, err = helper.mobileclient.MobileV1alpha1().MobileClients(os.Getenv(constants.EnvVarKeyNamespace)).Update(client)
+		err = helper.updateMobileClient(ctx, client)
 		if err != nil {
 			log.Printf("Unable to update mobile client %s. Error: %s", clientId, err.Error())
 		}
@@ -166,19 +171,81 @@ func (helper AnnotationHelperImpl) removeAnnotationFromMobileClient(clientId str
 
 }
 
+func (helper AnnotationHelperImpl) setPushServiceStatus(ctx context.Context, clientId string, service v1alpha1.MobileClientService) {
+	client, err := helper.getMobileClient(ctx, clientId)
+	if err != nil {
+		log.Printf("No mobile client with name %s found", clientId)
+		return
+	}
+
+	services := []v1alpha1.MobileClientService{}
+	for _, existing := range client.Status.Services {
+		if existing.Type != constants.PushServiceType {
+			services = append(services, existing)
+		}
+	}
+	client.Status.Services = append(services, service)
+
+	err = helper.updateMobileClient(ctx, client)
+	if err != nil {
+		log.Printf("Unable to update the push status of mobile client %s. Error: %s", clientId, err.Error())
+	}
+}
+
+func (helper AnnotationHelperImpl) removePushServiceStatus(ctx context.Context, clientId string) {
+	client, err := helper.getMobileClient(ctx, clientId)
+	if err != nil {
+		log.Printf("No mobile client with name %s found", clientId)
+		return
+	}
+
+	services := []v1alpha1.MobileClientService{}
+	for _, existing := range client.Status.Services {
+		if existing.Type != constants.PushServiceType {
+			services = append(services, existing)
+		}
+	}
+	if len(services) == len(client.Status.Services) {
+		return
+	}
+	client.Status.Services = services
+
+	err = helper.updateMobileClient(ctx, client)
+	if err != nil {
+		log.Printf("Unable to remove the push status of mobile client %s. Error: %s", clientId, err.Error())
+	}
+}
+
+func (helper AnnotationHelperImpl) getMobileClient(ctx context.Context, clientId string) (*v1alpha1.MobileClient, error) {
+	client := &v1alpha1.MobileClient{}
+	err := helper.mobileclient.MobileV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("mobileclients").
+		Name(clientId).
+		Do().
+		Into(client)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return client, nil
+}
+
+func (helper AnnotationHelperImpl) updateMobileClient(ctx context.Context, client *v1alpha1.MobileClient) error {
+	err := helper.mobileclient.MobileV1alpha1().RESTClient().Put().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("mobileclients").
+		Name(client.Name).
+		Body(client).
+		Do().
+		Error()
+	return contextError(ctx, err)
+}
+
 type variantAnnotationConfig struct {
 	Type      string `json:"type"`
 	TypeLabel string `json:"typeLabel"`
 	Url       string `json:"url"`
 	Id        string `json:"id"`
 }
-
-func getVariantTypeLabel(variantType string) string {
-	if strings.EqualFold(variantType, "android") {
-		return "Android"
-	} else if strings.EqualFold(variantType, "ios") {
-		return "iOS"
-	} else {
-		return variantType
-	}
-}
@@ -0,0 +1,123 @@
+package configOperator
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+type CircuitBreakerState string
+
+const (
+	// Requests are sent to UPS
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	// UPS is considered unavailable, requests fail immediately
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	// The open timeout has passed, a single trial request is sent to check if UPS is back
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+// Stops calling UPS after a number of consecutive failures. After a timeout a single trial
+// request is let through; if it succeeds the breaker closes again, otherwise it stays open.
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mutex           sync.Mutex
+	state           CircuitBreakerState
+	failures        int
+	openedAt        time.Time
+	trialInProgress bool
+	now             func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	breaker := new(CircuitBreaker)
+
+	breaker.failureThreshold = failureThreshold
+	breaker.openTimeout = openTimeout
+	breaker.state = CircuitBreakerClosed
+	breaker.now = time.Now
+
+	return breaker
+}
+
+func NewDefaultCircuitBreaker() *CircuitBreaker {
+	return NewCircuitBreaker(constants.UPSCircuitBreakerFailureThreshold, constants.UPSCircuitBreakerOpenTimeout*time.Second)
+}
+
+// Returns true if a request may be sent. Every allowed request must be followed by a call
+// to recordSuccess or recordFailure.
+func (breaker *CircuitBreaker) allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch breaker.currentState() {
+	case CircuitBreakerClosed:
+		return true
+	case CircuitBreakerHalfOpen:
+		if breaker.trialInProgress {
+			return false
+		}
+		breaker.state = CircuitBreakerHalfOpen
+		breaker.trialInProgress = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (breaker *CircuitBreaker) recordSuccess() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if breaker.state != CircuitBreakerClosed {
+		log.Print("UPS is available again, closing the circuit breaker")
+	}
+
+	breaker.state = CircuitBreakerClosed
+	breaker.failures = 0
+	breaker.trialInProgress = false
+}
+
+func (breaker *CircuitBreaker) recordFailure() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.failures++
+	breaker.trialInProgress = false
+
+	if breaker.state == CircuitBreakerHalfOpen || breaker.failures >= breaker.failureThreshold {
+		if breaker.state != CircuitBreakerOpen {
+			log.Printf("UPS failed %d times in a row, opening the circuit breaker for %s", breaker.failures, breaker.openTimeout)
+		}
+		breaker.state = CircuitBreakerOpen
+		breaker.openedAt = breaker.now()
+	}
+}
+
+// Releases an allowed request without recording an outcome, e.g. when the request was
+// cancelled before UPS responded
+func (breaker *CircuitBreaker) recordCancellation() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.trialInProgress = false
+}
+
+func (breaker *CircuitBreaker) getState() CircuitBreakerState {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.currentState()
+}
+
+// An open breaker becomes half-open once the timeout has passed. Must be called with the mutex held.
+func (breaker *CircuitBreaker) currentState() CircuitBreakerState {
+	if breaker.state == CircuitBreakerOpen && breaker.now().Sub(breaker.openedAt) >= breaker.openTimeout {
+		return CircuitBreakerHalfOpen
+	}
+	return breaker.state
+}
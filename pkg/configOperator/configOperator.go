@@ -1,20 +1,22 @@
 package configOperator
 
 import (
-	"strconv"
+	"context"
 
 	"encoding/json"
 
 	"log"
 
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/satori/go.uuid"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"github.com/pkg/errors"
 	"k8s.io/api/core/v1"
 )
 
@@ -24,60 +26,115 @@ type ConfigOperator struct {
 	pushClientProvider UpsClientProvider
 	annotationHelper   AnnotationHelper
 	kubeHelper         KubeHelper
+	eventHelper        EventHelper
+	platforms          *PlatformRegistry
+	// Interval in which UPS is checked for deleted variants
+	pollingInterval time.Duration
+	health          *Health
+	// Number of polls a variant that cannot be looked up has to be missing from UPS in before
+	// its service binding is deleted, and the maximum number of bindings deleted per poll
+	driftMissedPolls  int
+	driftMaxDeletions int
+	missingVariants   *pollStreaks
+	// What the poll does with variants in UPS that have no config secret
+	orphanedVariantPolicy OrphanedVariantPolicy
+	orphanedVariants      *pollStreaks
 }
 
-func NewConfigOperator(pushClientProvider UpsClientProvider, annotationHelper AnnotationHelper, kubeHelper KubeHelper) *ConfigOperator {
+func NewConfigOperator(pushClientProvider UpsClientProvider, annotationHelper AnnotationHelper, kubeHelper KubeHelper, eventHelper EventHelper, platforms *PlatformRegistry, pollingInterval time.Duration, health *Health, driftMissedPolls int, driftMaxDeletions int, orphanedVariantPolicy OrphanedVariantPolicy) *ConfigOperator {
 	op := new(ConfigOperator)
 
 	op.pushClientProvider = pushClientProvider
 	op.annotationHelper = annotationHelper
 	op.kubeHelper = kubeHelper
+	op.eventHelper = eventHelper
+	op.platforms = platforms
+	op.pollingInterval = pollingInterval
+	op.health = health
+	op.driftMissedPolls = driftMissedPolls
+	op.driftMaxDeletions = driftMaxDeletions
+	op.missingVariants = newPollStreaks()
+	op.orphanedVariantPolicy = orphanedVariantPolicy
+	op.orphanedVariants = newPollStreaks()
 
 	return op
 }
 
-func (op ConfigOperator) StartService() {
-	log.Print("Entering watch loop")
+// Blocks until ctx is done and the in-flight work has finished. Once ctx is done no new
+// events are handled and the poller stops, but a binding that is being handled is completed
+// so that variants, config secrets and annotations stay consistent. UPS and Kubernetes calls
+// that are still running after gracePeriod are cancelled.
+func (op ConfigOperator) StartService(ctx context.Context, gracePeriod time.Duration) {
+	stop := ctx.Done()
+
+	op.health.setRunning(true)
+	defer op.health.setRunning(false)
+
+	// In-flight work uses its own context so that it isn't cancelled right away on shutdown
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	go func() {
+		select {
+		case <-stop:
+		case <-workCtx.Done():
+			return
+		}
+		log.Printf("Shutting down, waiting up to %s for in-flight work", gracePeriod)
+		select {
+		case <-time.After(gracePeriod):
+			log.Print("Grace period exceeded, cancelling in-flight work")
+			cancelWork()
+		case <-workCtx.Done():
+		}
+	}()
 
-	// poll UPS in a separate thread
-	go op.startPollingUPS()
+	op.reconcile(workCtx, stop)
 
-	// call startKubeWatchLoop inside an endless loop
-	// this is blocking so any code called after it will not be run
-	// the reason for this is because the k8s watcher dies if an error/timeout occurs
-	for {
-		op.startKubeWatchLoop()
-	}
+	// poll UPS in a separate thread
+	var poller sync.WaitGroup
+	poller.Add(1)
+	go func() {
+		defer poller.Done()
+		op.startPollingUPS(workCtx, stop)
+	}()
+
+	// this is blocking until stop is closed and the current item is handled
+	newSecretController(op).run(workCtx, stop)
+
+	poller.Wait()
+	log.Print("Operator stopped")
 }
 
 // startPollingUPS() is a loop that calls compareUPSVariantsWithClientConfigs() in intervals
-func (op ConfigOperator) startPollingUPS() {
-	interval := constants.UPSPollingInterval * time.Second
+// until stop is closed
+func (op ConfigOperator) startPollingUPS(ctx context.Context, stop <-chan struct{}) {
+	op.health.setPolling(true)
+	defer op.health.setPolling(false)
+
 	for {
-		<-time.After(interval)
-		op.compareUPSVariantsWithClientConfigs()
+		select {
+		case <-stop:
+			return
+		case <-time.After(op.pollingInterval):
+			op.compareUPSVariantsWithClientConfigs(ctx)
+			op.health.pollProgressed()
+		}
 	}
 }
 
-func (op ConfigOperator) startKubeWatchLoop() {
-	events, err := op.kubeHelper.startSecretWatch()
-	if err != nil {
-		panic(err.Error())
-	}
-
-	for update := range events.ResultChan() {
-		switch action := update.Type; action {
-		case constants.K8SecretEventTypeAdded:
-			op.handleAddSecret(update.Object)
-		case constants.K8SecretEventTypeDeleted:
-			op.handleDeleteSecret(update.Object)
-		default:
-			log.Print("Unhandled action:", action)
-		}
+// Returns true once stop is closed
+func isStopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
 	}
 }
 
-func (op ConfigOperator) handleAddSecret(obj runtime.Object) {
+// Stores a binding secret as a PushVariant, the variant is created in UPS once the PushVariant
+// is synced. Returns an error if the secret should be handled again later.
+func (op ConfigOperator) handleAddSecret(ctx context.Context, obj runtime.Object) error {
 	raw, _ := json.Marshal(obj)
 	var secret = BindingSecret{}
 	json.Unmarshal(raw, &secret)
@@ -85,25 +142,52 @@ func (op ConfigOperator) handleAddSecret(obj runtime.Object) {
 		appType := string(secret.Data[constants.BindingDataAppTypeKey])
 		log.Printf("A mobile binding secret of type `%s` was added", appType)
 
-		if appType == "Android" {
-			op.handleAndroidVariant(&secret)
-		} else if appType == "IOS" {
-			op.handleIOSVariant(&secret)
+		// Unknown app types share a label to keep the number of labels small
+		platformLabel, outcome := "unknown", bindingOutcomeUnsupported
+		if platform := op.platforms.get(appType); platform != nil {
+			platformLabel, outcome = platform.getName(), bindingOutcomeSuccess
+
+			// Keep the secret if the PushVariant cannot be stored or the operator is shutting
+			// down. It is handled again after a backoff or when the operator is restarted.
+			if err := op.savePushVariant(ctx, platform, &secret); err != nil {
+				log.Printf("Cannot store the %s binding of client `%s`, keeping binding secret `%s` to retry later: %s",
+					platform.getName(), string(secret.Data[constants.BindingDataClientIdKey]), secret.Name, err.Error())
+				bindingSecretsProcessed.WithLabelValues(platformLabel, bindingOutcomeRetry).Inc()
+				return err
+			}
 		}
-		// Always delete the secret after handling it regardless of any new resources
-		// was created
-		op.kubeHelper.deleteSecret(secret.Name)
+		bindingSecretsProcessed.WithLabelValues(platformLabel, outcome).Inc()
+
+		// Always delete the secret after handling it, the credentials are kept in the
+		// secret of the PushVariant
+		op.kubeHelper.deleteSecret(ctx, secret.Name)
+	}
+	return nil
+}
+
+func (op ConfigOperator) logVariantCreationError(appType string, clientId string, err error) {
+	switch {
+	case IsUpsValidationFailed(err):
+		log.Printf("UPS rejected the %s variant for client `%s`, please check the binding parameters: %s", appType, clientId, err.Error())
+	case IsUpsUnauthorized(err):
+		log.Printf("Not authorized to create the %s variant for client `%s`, please check the credentials in the UPS secret: %s", appType, clientId, err.Error())
+	case IsUpsConflict(err):
+		log.Printf("A conflicting %s variant for client `%s` already exists in UPS: %s", appType, clientId, err.Error())
+	case IsUpsUnavailable(err):
+		log.Printf("UPS is not available, no %s variant has been created for client `%s`: %s", appType, clientId, err.Error())
+	default:
+		log.Printf("Error creating %s variant for client `%s`: %s", appType, clientId, err.Error())
 	}
 }
 
-func (op ConfigOperator) handleDeleteSecret(obj runtime.Object) {
+func (op ConfigOperator) handleDeleteSecret(ctx context.Context, obj runtime.Object) {
 	raw, _ := json.Marshal(obj)
 	var secret = BindingSecret{}
 	json.Unmarshal(raw, &secret)
 
 	for _, ref := range secret.ObjectMeta.OwnerReferences {
 		if ref.Kind == "ServiceBinding" {
-			op.handleDeleteVariant(&secret)
+			op.deleteVariantOfBinding(ctx, &secret)
 			break
 		}
 	}
@@ -112,35 +196,48 @@ func (op ConfigOperator) handleDeleteSecret(obj runtime.Object) {
 // compareUPSVariantsWithClientConfigs() compares the UPS client configs stored in k8's secrets
 // against the variants in UPS in order to detect if a variant has been deleted in UPS
 // If a client config is found that references a variant not found in UPS then we clean up the client config by deleting the associated servicebinding.
-func (op ConfigOperator) compareUPSVariantsWithClientConfigs() {
-	pushClient := op.pushClientProvider.getPushClient()
+// Bindings are only deleted once the variant is known to be gone, see deleteBindingsOfDeletedVariants.
+func (op ConfigOperator) compareUPSVariantsWithClientConfigs(ctx context.Context) {
+	pushClient := op.pushClientProvider.getPushClient(ctx)
 	if pushClient == nil {
 		log.Printf("Cannot compare UPS variants with client configs since the push client cannot be built")
 		return
 	}
 
+	if !pushClient.isAvailable() {
+		log.Printf("Skipping the comparison of UPS variants with client configs since UPS is unavailable")
+		return
+	}
+
 	// get the UPS related secrets
 	selector := fmt.Sprintf("serviceName=ups,pushApplicationId=%s", pushClient.getApplicationId())
-	secretsList, err := op.kubeHelper.listSecrets(selector)
-	secrets := secretsList.Items
-
+	secretsList, err := op.kubeHelper.listSecrets(ctx, selector)
 	if err != nil {
 		log.Printf("Error searching for ups secrets: %v", err.Error())
 		return
 	}
+	// The variants of secrets that are being deleted are deleted by the finalizer
+	var secrets []v1.Secret
+	for _, secret := range secretsList.Items {
+		if secret.DeletionTimestamp == nil {
+			secrets = append(secrets, secret)
+		}
+	}
+	configSecrets.Set(float64(len(secrets)))
 
 	// process the secrets into a list of VariantServiceBindingMappings
 	// each element has VariantId and ServiceBindingId
 	clientConfigs := op.getUPSVariantServiceBindingMappings(secrets)
 
 	// Get all variants from UPS
-	UPSVariants, err := pushClient.getVariants()
+	UPSVariants, err := pushClient.getVariants(ctx)
 
 	if err != nil {
 		log.Printf("An error occurred trying to get variants from UPS service: %v", err.Error())
 		return
 	}
 
+	var missing []VariantServiceBindingMapping
 	for _, clientConfig := range clientConfigs {
 		found := false
 
@@ -152,13 +249,15 @@ func (op ConfigOperator) compareUPSVariantsWithClientConfigs() {
 		}
 
 		if !found {
-			fmt.Printf("variant Id %v found in client configs but not found in UPS. Should delete", clientConfig.VariantId)
-			err := op.handleDeleteServiceBinding(clientConfig.ServiceBindingId)
-			if err != nil {
-				log.Printf("Error deleting service binding instance with id %s\n%s", clientConfig.ServiceBindingId, err.Error())
-			}
+			missing = append(missing, clientConfig)
 		}
 	}
+	op.deleteBindingsOfDeletedVariants(ctx, pushClient, missing)
+
+	// Variants of config secrets that are being deleted are not orphaned
+	op.handleOrphanedVariants(ctx, pushClient, secretsList.Items)
+
+	lastSuccessfulPoll.Set(float64(time.Now().Unix()))
 }
 
 // getUPSVariantServiceBindingMappings() takes the list of secrets and returns a list of VariantServiceBindingMappings
@@ -166,11 +265,13 @@ func (op ConfigOperator) getUPSVariantServiceBindingMappings(secrets []v1.Secret
 
 	var results []VariantServiceBindingMapping
 
-	buildAndAppendResult := func(results []VariantServiceBindingMapping, variantId string, serviceBindingId string, secret v1.Secret) []VariantServiceBindingMapping {
+	buildAndAppendResult := func(results []VariantServiceBindingMapping, platform string, variantId string, serviceBindingId string, secret v1.Secret) []VariantServiceBindingMapping {
 		if variantServiceBindingMapping, err := GetClientConfigRepresentation(variantId, serviceBindingId); err != nil {
 			log.Printf("invalid android UPS client config found in secret %s reason: %s", secret.Name, err.Error())
 			return results
 		} else {
+			variantServiceBindingMapping.ClientId = secret.Labels["clientId"]
+			variantServiceBindingMapping.Platform = platform
 			return append(results, variantServiceBindingMapping)
 		}
 	}
@@ -181,116 +282,166 @@ func (op ConfigOperator) getUPSVariantServiceBindingMappings(secrets []v1.Secret
 		clientConfig := UPSClientConfig{}
 		json.Unmarshal(secret.Data["config"], &clientConfig)
 
-		if clientConfig.Android != nil {
-			androidConfig := *clientConfig.Android
-			variantId := androidConfig["variantId"]
-			serviceBindingId := secret.ObjectMeta.Annotations["binding/android"]
-			results = buildAndAppendResult(results, variantId, serviceBindingId, secret)
-		}
-
-		if clientConfig.IOS != nil {
-			iOSConfig := *clientConfig.IOS
-			variantId := iOSConfig["variantId"]
-			serviceBindingId := secret.ObjectMeta.Annotations["binding/ios"]
-			results = buildAndAppendResult(results, variantId, serviceBindingId, secret)
+		for _, platform := range op.platforms.all() {
+			if platformConfig, ok := clientConfig.get(platform.getName()); ok {
+				variantId := platformConfig["variantId"]
+				serviceBindingId := secret.ObjectMeta.Annotations[fmt.Sprintf("binding/%s", platform.getName())]
+				results = buildAndAppendResult(results, platform.getName(), variantId, serviceBindingId, secret)
+			}
 		}
 	}
 	return results
 }
 
-func (op ConfigOperator) handleDeleteServiceBinding(servicebindingId string) error {
-	serviceBindingName, err := op.kubeHelper.getServiceBindingNameByID(servicebindingId)
+// Returns false if the service binding is protected from deletion
+func (op ConfigOperator) handleDeleteServiceBinding(ctx context.Context, servicebindingId string) (bool, error) {
+	serviceBinding, err := op.kubeHelper.getServiceBindingByID(ctx, servicebindingId)
 	if err != nil {
-		return err
+		return false, err
 	}
-	err = op.kubeHelper.deleteServiceBinding(serviceBindingName)
-	return err
+	if serviceBinding.Annotations[constants.ProtectedBindingAnnotation] == "true" {
+		return false, nil
+	}
+	err = op.kubeHelper.deleteServiceBinding(ctx, serviceBinding.Name)
+	return err == nil, err
 }
 
-func (op ConfigOperator) handleAndroidVariant(secret *BindingSecret) {
+// Creates a variant for the binding or updates the credentials of the client's existing variant.
+// Returns the ID of the variant and the name of the config secret it has been written to.
+func (op ConfigOperator) handleVariant(ctx context.Context, platform Platform, secret *BindingSecret) (string, string, error) {
 	clientId := string(secret.Data[constants.BindingDataClientIdKey])
-	googleKey := string(secret.Data[constants.BindingDataGoogleKey])
-	projectNumber := string(secret.Data[constants.BindingDataProjectNumberKey])
 	serviceBindingId := string(secret.Data[constants.BindingDataServiceBindingIdKey])
 	serviceInstanceName := string(secret.Data[constants.BindingDataServiceInstanceNameKey])
 
-	payload := &AndroidVariant{
-		ProjectNumber: projectNumber,
-		GoogleKey:     googleKey,
-		Variant: Variant{
-			Name:      clientId,
-			VariantID: uuid.NewV4().String(),
-			Secret:    uuid.NewV4().String(),
-		},
+	payload, err := platform.parseBinding(secret)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid %s binding for client %s", platform.getName(), clientId)
 	}
+	payload.getVariant().Name = clientId
+	newVariantCredentials(payload.getVariant())
 
-	log.Print("Creating a new android variant", payload)
-	success, variant := op.pushClientProvider.getPushClient().createAndroidVariant(payload)
-	if success {
-		config, _ := variant.getJson()
-		op.updateConfiguration("android", clientId, variant.VariantID, config, serviceBindingId, serviceInstanceName)
+	pushClient := op.pushClientProvider.getPushClient(ctx)
+	if pushClient == nil {
+		return "", "", errNoPushClient
+	}
+
+	existingVariantId, existingSecret, err := op.getExistingVariant(ctx, clientId, platform.getName())
+	if err != nil {
+		return "", "", err
+	}
+
+	// The variant might have been created without the config secret being written, e.g. when
+	// the operator was stopped in between. Adopt it instead of creating a duplicate.
+	if existingVariantId == "" {
+		upsVariant, err := platform.findVariant(ctx, pushClient, clientId)
+		if err != nil {
+			return "", "", errors.Wrap(err, "cannot look up existing variants")
+		}
+		if upsVariant != nil {
+			log.Printf("Found %s variant %s for client %s in UPS, adopting it", platform.getName(), upsVariant.VariantID, clientId)
+			existingVariantId = upsVariant.VariantID
+			existingSecret = upsVariant.Secret
+		}
+	}
+
+	var variant PlatformVariant
+	// Set when a new variant replaces the existing one
+	var replacedVariantId string
+	reason := eventReasonVariantUpdated
+	if existingVariantId != "" {
+		// Keep the variantId and secret stable so that the app does not need a new config
+		payload.getVariant().VariantID = existingVariantId
+		payload.getVariant().Secret = existingSecret
+
+		log.Printf("Updating %s variant %s", platform.getName(), existingVariantId)
+		variant, err = platform.updateVariant(ctx, pushClient, payload)
+		if IsUpsNotFound(err) {
+			log.Printf("The %s variant %s does not exist in UPS anymore, creating a new one", platform.getName(), existingVariantId)
+			newVariantCredentials(payload.getVariant())
+			variant, err = platform.createVariant(ctx, pushClient, payload)
+			reason = eventReasonVariantCreated
+			replacedVariantId = existingVariantId
+		}
 	} else {
-		log.Println("No variant has been created in UPS, skipping config secret")
+		log.Printf("Creating a new %s variant for client %s", platform.getName(), clientId)
+		variant, err = platform.createVariant(ctx, pushClient, payload)
+		reason = eventReasonVariantCreated
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "no variant has been created or updated in UPS, skipping config secret")
 	}
-}
 
-func (op ConfigOperator) handleIOSVariant(secret *BindingSecret) {
-	clientId := string(secret.Data[constants.BindingDataClientIdKey])
-	cert := string(secret.Data[constants.BindingDataIOSCertKey])
-	passPhrase := string(secret.Data[constants.BindingDataIOSPassPhraseKey])
-	serviceBindingId := string(secret.Data[constants.BindingDataServiceBindingIdKey])
-	serviceInstanceName := string(secret.Data[constants.BindingDataServiceInstanceNameKey])
-	isProductionString := string(secret.Data[constants.BindingDataIOSIsProductionKey])
-	isProduction, err := strconv.ParseBool(isProductionString)
+	message := fmt.Sprintf("The %s variant %s has been updated in UPS", platform.getName(), variant.getVariant().VariantID)
+	if reason == eventReasonVariantCreated {
+		message = fmt.Sprintf("The %s variant %s has been created in UPS", platform.getName(), variant.getVariant().VariantID)
+	}
+	op.recordEvent(ctx, clientId, serviceBindingId, v1.EventTypeNormal, reason, message)
 
+	config, _ := variant.getJson()
+	configSecretName, err := op.updateConfiguration(ctx, pushClient, platform.getName(), clientId, variant.getVariant().VariantID, config, serviceBindingId, serviceInstanceName)
 	if err != nil {
-		log.Printf("iOS variant with clientId %v is invalid, isProduction value %v should be true or false. Setting to false", clientId, isProductionString)
-		isProduction = false
+		return "", "", err
 	}
 
-	certByteArray := []byte(cert)
-	payload := &IOSVariant{
-		Certificate: certByteArray,
-		Passphrase:  passPhrase,
-		Production:  isProduction, //false for now while testing functionality
-		Variant: Variant{
-			Name:      clientId,
-			VariantID: uuid.NewV4().String(),
-			Secret:    uuid.NewV4().String(),
-		},
+	if replacedVariantId != "" {
+		op.deleteReplacedVariant(ctx, pushClient, platform, replacedVariantId)
 	}
+	return variant.getVariant().VariantID, configSecretName, nil
+}
 
-	success, variant := op.pushClientProvider.getPushClient().createIOSVariant(payload)
-	if success {
-		config, _ := variant.getJson()
-		op.updateConfiguration("ios", clientId, variant.VariantID, config, serviceBindingId, serviceInstanceName)
-	} else {
-		log.Print("No variant has been created in UPS, skipping config secret")
+// Deletes the variant that a new variant has been created for, once the config secret refers to
+// the new one. UPS keeps iOS variants with a certificate and with a token as separate resources,
+// so switching between the two doesn't find the old variant and would otherwise leave it behind
+// with the old credentials. A failed deletion leaves an orphaned variant, see handleOrphanedVariants.
+func (op ConfigOperator) deleteReplacedVariant(ctx context.Context, pushClient UpsClient, platform Platform, variantId string) {
+	err := platform.deleteVariant(ctx, pushClient, &BindingSecret{}, variantId)
+	if err != nil && !IsUpsNotFound(err) {
+		log.Printf("Cannot delete the replaced %s variant %s: %s", platform.getName(), variantId, err.Error())
+		return
+	}
+	if err == nil {
+		log.Printf("The replaced %s variant %s has been deleted", platform.getName(), variantId)
 	}
 }
 
-// Deletes a configuration from the config secret and from the UPS server
-func (op ConfigOperator) handleDeleteVariant(secret *BindingSecret) {
-	appType := strings.ToLower(string(secret.Data["appType"]))
+func newVariantCredentials(variant *Variant) {
+	variant.VariantID = uuid.NewV4().String()
+	variant.Secret = uuid.NewV4().String()
+}
 
+// Deletes a configuration from the config secret and from the UPS server
+func (op ConfigOperator) handleDeleteVariant(ctx context.Context, secret *BindingSecret) {
 	// Check if the deleted secret is related to some UPS binding.
-	if appType != "android" && appType != "ios" {
+	platform := op.platforms.get(string(secret.Data["appType"]))
+	if platform == nil {
 		return
 	}
 
-	success, variantId := op.removeConfigFromClientSecret(secret, appType)
+	success, variantId := op.removeConfigFromClientSecret(ctx, secret, platform.getName())
 
 	if success {
-		success := op.pushClientProvider.getPushClient().deleteVariant(appType, variantId)
-		if !success {
-			log.Printf("UPS reported an error when deleting variant %s", variantId)
+		pushClient := op.pushClientProvider.getPushClient(ctx)
+		if pushClient == nil {
+			log.Printf("Cannot delete variant %s since the push client cannot be built", variantId)
+			return
 		}
+
+		err := platform.deleteVariant(ctx, pushClient, secret, variantId)
+		if IsUpsNotFound(err) {
+			log.Printf("Variant %s does not exist in UPS, nothing to delete", variantId)
+		} else if err != nil {
+			log.Printf("UPS reported an error when deleting variant %s: %s", variantId, err.Error())
+			return
+		}
+
+		op.eventHelper.mobileClientEvent(ctx, string(secret.Data[constants.BindingDataClientIdKey]), v1.EventTypeNormal, eventReasonVariantDeleted,
+			fmt.Sprintf("The %s variant %s has been deleted from UPS", platform.getName(), variantId))
 	}
 }
 
 // Removes a platform configuration (e.g. iOS or Android) from the `Data.config` map of a UPS configuration
 // secret. If there is only one platform it will delete the whole secret.
-func (op ConfigOperator) removeConfigFromClientSecret(secret *BindingSecret, appType string) (bool, string) {
+func (op ConfigOperator) removeConfigFromClientSecret(ctx context.Context, secret *BindingSecret, appType string) (bool, string) {
 	clientId := string(secret.Data["clientId"])
 
 	if clientId == "" {
@@ -298,18 +449,31 @@ func (op ConfigOperator) removeConfigFromClientSecret(secret *BindingSecret, app
 		return false, ""
 	}
 
-	configSecret := op.kubeHelper.findMobileClientConfig(clientId)
+	configSecret, err := op.kubeHelper.findMobileClientConfig(ctx, clientId)
+	if err != nil {
+		log.Printf("Cannot delete configuration for client `%s`: %s", clientId, err.Error())
+		return false, ""
+	}
 
 	if configSecret == nil {
 		log.Printf("Cannot delete configuration for client `%s` because the secret does not exist", clientId)
 		return false, ""
 	}
 
+	// The variant has been taken over by a newer binding with updated credentials.
+	// Removing it now would delete the variant that the newer binding still uses.
+	bindingId := string(secret.Data[constants.BindingDataServiceBindingIdKey])
+	currentBindingId, ok := configSecret.Annotations[fmt.Sprintf("binding/%s", appType)]
+	if bindingId != "" && ok && currentBindingId != bindingId {
+		log.Printf("The %s configuration of %s belongs to binding %s, keeping it", appType, clientId, currentBindingId)
+		return false, ""
+	}
+
 	serviceInstanceName := string(configSecret.Data[constants.BindingDataServiceInstanceNameKey])
 	log.Printf("Deleting %s configuration from %s", appType, clientId)
 
 	// Remove the annotation also from the mobile client
-	op.annotationHelper.removeAnnotationFromMobileClient(clientId, appType, serviceInstanceName)
+	op.annotationHelper.removeAnnotationFromMobileClient(ctx, clientId, appType, serviceInstanceName)
 
 	// Get the current config
 	// Retrieve the current config as an object
@@ -323,7 +487,10 @@ func (op ConfigOperator) removeConfigFromClientSecret(secret *BindingSecret, app
 	// If there is only one platform in the configuration we can remove the whole
 	// secret
 	if len(currentConfig) == 1 {
-		op.kubeHelper.deleteSecret(configSecret.Name)
+		op.kubeHelper.deleteSecret(ctx, configSecret.Name)
+		op.annotationHelper.removePushServiceStatus(ctx, clientId)
+		op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeNormal, eventReasonConfigSecretUpdated,
+			fmt.Sprintf("The config secret %s has been deleted since the %s configuration was the last one", configSecret.Name, appType))
 		return true, variantId
 	} else {
 		log.Println("More than one variant available, updating configuration object")
@@ -339,30 +506,73 @@ func (op ConfigOperator) removeConfigFromClientSecret(secret *BindingSecret, app
 		}
 
 		configSecret.Data["config"] = currentConfigString
-		_, err = op.kubeHelper.updateSecret(configSecret)
+		_, err = op.kubeHelper.updateSecret(ctx, configSecret)
 		if err != nil {
 			log.Println(err.Error())
+		} else {
+			op.annotationHelper.setPushServiceStatus(ctx, clientId, pushServiceFromConfigSecret(configSecret))
+			op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeNormal, eventReasonConfigSecretUpdated,
+				fmt.Sprintf("The %s configuration has been removed from the config secret %s", appType, configSecret.Name))
 		}
 
 		return true, variantId
 	}
 }
 
+// Returns the variantId and secret of the given platform from the config secret of a mobile client.
+// Both are empty if the client has no variant for that platform yet.
+func (op ConfigOperator) getExistingVariant(ctx context.Context, clientId string, appType string) (string, string, error) {
+	configSecret, err := op.kubeHelper.findMobileClientConfig(ctx, clientId)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot look up the config secret")
+	}
+
+	if configSecret == nil {
+		return "", "", nil
+	}
+
+	var currentConfig map[string]json.RawMessage
+	json.Unmarshal(configSecret.Data["config"], &currentConfig)
+
+	configMap := make(map[string]string)
+	json.Unmarshal(currentConfig[appType], &configMap)
+	return configMap["variantId"], configMap["variantSecret"], nil
+}
+
 func (op ConfigOperator) getVariantIdFromConfig(config string) string {
 	configMap := make(map[string]string)
 	json.Unmarshal([]byte(config), &configMap)
 	return configMap["variantId"]
 }
 
+// Builds the push entry of the mobile client status from its config secret. The config
+// holds the variant ID and secret of every platform.
+func pushServiceFromConfigSecret(configSecret *v1.Secret) v1alpha1.MobileClientService {
+	return v1alpha1.MobileClientService{
+		Id:      configSecret.Labels["pushApplicationId"],
+		Name:    string(configSecret.Data["name"]),
+		Type:    constants.PushServiceType,
+		Url:     string(configSecret.Data["uri"]),
+		Config:  json.RawMessage(configSecret.Data["config"]),
+		Version: constants.PushServiceVersion,
+	}
+}
+
 // Updates the `Data.config` map of a UPS configuration secret
-// The secret can contain multiple variants (e.g. iOS and Android) but is bound to one mobile client
-func (op ConfigOperator) updateConfiguration(appType string, clientId string, variantId string, newConfig []byte, bindingId string, serviceInstanceName string) {
-	configSecret := op.kubeHelper.findMobileClientConfig(clientId)
-	pushClient := op.pushClientProvider.getPushClient()
+// The secret can contain multiple variants (e.g. iOS and Android) but is bound to one mobile client.
+// Returns the name of the secret.
+func (op ConfigOperator) updateConfiguration(ctx context.Context, pushClient UpsClient, appType string, clientId string, variantId string, newConfig []byte, bindingId string, serviceInstanceName string) (string, error) {
+	configSecret, err := op.kubeHelper.findMobileClientConfig(ctx, clientId)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot look up the config secret")
+	}
 
 	if configSecret == nil {
 		// No config secret exists for this client yet. Create one.
-		configSecret = op.kubeHelper.createClientConfigSecret(clientId, serviceInstanceName, pushClient.getServiceInstanceId(), pushClient.getApplicationId())
+		configSecret, err = op.kubeHelper.createClientConfigSecret(ctx, clientId, serviceInstanceName, pushClient.getServiceInstanceId(), pushClient.getApplicationId())
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// Retrieve the current config as an object
@@ -393,19 +603,36 @@ func (op ConfigOperator) updateConfiguration(appType string, clientId string, va
 	}
 	configSecret.Annotations[bindingAnnotation] = bindingId
 
-	pushApplicationName, err := pushClient.getPushApplicationName()
+	// Secrets created by older versions of the operator have no finalizer and owner yet
+	addFinalizer(&configSecret.ObjectMeta, constants.ConfigSecretFinalizer)
+	op.setConfigSecretOwner(ctx, configSecret, clientId)
+
+	pushApplicationName, err := pushClient.getPushApplicationName(ctx)
 	if err != nil {
 		// don't fail because of name not fetched. just use the id as the name
 		pushApplicationName = pushClient.getApplicationId()
 	}
 
 	log.Println("Adding annotations to mobile client")
-	op.annotationHelper.addAnnotationToMobileClient(clientId, pushClient.getBaseUrl(), pushClient.getApplicationId(), pushApplicationName, appType, variantId, serviceInstanceName)
+	op.annotationHelper.addAnnotationToMobileClient(ctx, clientId, pushClient.getBaseUrl(), pushClient.getApplicationId(), pushApplicationName, appType, variantId, serviceInstanceName)
 
-	_, err = op.kubeHelper.updateSecret(configSecret)
+	_, err = op.kubeHelper.updateSecret(ctx, configSecret)
 	if err != nil {
-		log.Println(err.Error())
-	} else {
-		log.Printf("%s configuration of %s has been updated", appType, clientId)
+		return "", errors.Wrap(err, "cannot update the config secret")
+	}
+
+	op.annotationHelper.setPushServiceStatus(ctx, clientId, pushServiceFromConfigSecret(configSecret))
+
+	log.Printf("%s configuration of %s has been updated", appType, clientId)
+	op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeNormal, eventReasonConfigSecretUpdated,
+		fmt.Sprintf("The %s configuration in the config secret %s has been updated", appType, configSecret.Name))
+	return configSecret.Name, nil
+}
+
+// Records an event on the mobile client and, if the binding is known, on the service binding
+func (op ConfigOperator) recordEvent(ctx context.Context, clientId string, bindingId string, eventType string, reason string, message string) {
+	op.eventHelper.mobileClientEvent(ctx, clientId, eventType, reason, message)
+	if bindingId != "" {
+		op.eventHelper.serviceBindingEvent(ctx, bindingId, eventType, reason, message)
 	}
 }
@@ -1,12 +1,22 @@
 package configOperator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"github.com/stretchr/testify/mock"
 	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
 )
 
 var op *ConfigOperator;
@@ -15,16 +25,38 @@ var pushClientProvider *MockUpsClientProvider
 var pushClient *MockUpsClient
 var annotationHelper *MockAnnotationHelper
 var kubeHelper *MockKubeHelper
+var eventHelper *MockEventHelper
 
 func setup() {
 	pushClientProvider = new(MockUpsClientProvider)
 	pushClient = new(MockUpsClient)
 	annotationHelper = new(MockAnnotationHelper)
 	kubeHelper = new(MockKubeHelper)
+	eventHelper = new(MockEventHelper)
 
-	pushClientProvider.On("getPushClient").Return(pushClient)
+	pushClientProvider.On("getPushClient", mock.Anything).Return(pushClient)
+	pushClientProvider.On("getAdminClient", mock.Anything).Return(pushClient)
+	eventHelper.On("mobileClientEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	eventHelper.On("serviceBindingEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	annotationHelper.On("setPushServiceStatus", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	annotationHelper.On("removePushServiceStatus", mock.Anything, mock.Anything).Maybe()
+	kubeHelper.On("getMobileClient", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
 
-	op = NewConfigOperator(pushClientProvider, annotationHelper, kubeHelper)
+	op = NewConfigOperator(pushClientProvider, annotationHelper, kubeHelper, eventHelper, NewDefaultPlatformRegistry(), constants.UPSPollingInterval*time.Second, NewHealth(time.Minute), constants.DriftMissedPolls, constants.DriftMaxDeletions, OrphanedVariantPolicyReport)
+}
+
+// Syncs the PushVariant the binding secret is stored as, the binding secret holds the credentials
+func syncBindingSecret(secret *BindingSecret) error {
+	platform := op.platforms.get(string(secret.Data["appType"]))
+	pushVariant := &push.PushVariant{
+		Spec: pushVariantSpecOfBinding(platform, secret),
+	}
+	pushVariant.Name = "myPushVariant"
+
+	kubeHelper.On("getSecret", mock.Anything, pushVariant.Spec.SecretRef.Name).Return(secret, nil)
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+
+	return op.syncPushVariant(context.Background(), pushVariant)
 }
 
 func TestConfigOperator_compareUPSVariantsWithClientConfigs(t *testing.T) {
@@ -59,17 +91,36 @@ func TestConfigOperator_compareUPSVariantsWithClientConfigs(t *testing.T) {
 		{VariantID: "foo"},
 	}
 
+	pushClient.On("isAvailable").Return(true)
 	pushClient.On("getApplicationId").Return("myapp")
-	kubeHelper.On("listSecrets", "serviceName=ups,pushApplicationId=myapp").Return(secretList, nil)
-	pushClient.On("getVariants").Return(variantList, nil)
-	kubeHelper.On("getServiceBindingNameByID", "toBeDeleted").Return("nameOfTheServiceBindingToDelete", nil)
-	kubeHelper.On("deleteServiceBinding", "nameOfTheServiceBindingToDelete").Return(nil)
-
-	op.compareUPSVariantsWithClientConfigs()
+	kubeHelper.On("listSecrets", mock.Anything, "serviceName=ups,pushApplicationId=myapp").Return(secretList, nil)
+	pushClient.On("getVariants", mock.Anything).Return(variantList, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, "android").Return(variantList, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return(nil, nil)
+	// the variant is confirmed to be deleted
+	pushClient.On("getVariant", mock.Anything, mock.Anything, "bar").Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	serviceBinding := &v1beta1.ServiceBinding{}
+	serviceBinding.Name = "nameOfTheServiceBindingToDelete"
+	kubeHelper.On("getServiceBindingByID", mock.Anything, "toBeDeleted").Return(serviceBinding, nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, "nameOfTheServiceBindingToDelete").Return(nil)
+
+	op.compareUPSVariantsWithClientConfigs(context.Background())
 
 	kubeHelper.AssertExpectations(t)
 }
 
+func TestConfigOperator_compareUPSVariantsWithClientConfigs_whenUPSIsUnavailable(t *testing.T) {
+	setup()
+
+	pushClient.On("isAvailable").Return(false)
+
+	op.compareUPSVariantsWithClientConfigs(context.Background())
+
+	pushClient.AssertNotCalled(t, "getVariants", mock.Anything)
+	kubeHelper.AssertNotCalled(t, "listSecrets", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, mock.Anything)
+}
+
 func TestConfigOperator_handleDeleteSecret_whenThereAre2Variants(t *testing.T) {
 	setup()
 
@@ -98,14 +149,16 @@ func TestConfigOperator_handleDeleteSecret_whenThereAre2Variants(t *testing.T) {
 		"binding/ios":     "toBeKept",
 	}
 
-	kubeHelper.On("findMobileClientConfig", "myClientId").Return(configSecret)
-	annotationHelper.On("removeAnnotationFromMobileClient", "myClientId", "android", "myServiceInstanceName").Once()
-	kubeHelper.On("updateSecret", mock.Anything).Return(nil, nil)
-	pushClient.On("deleteVariant", "android", "myVariantId").Return(true)
+	// created before bindings were stored as PushVariants
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	annotationHelper.On("removeAnnotationFromMobileClient", mock.Anything, "myClientId", "android", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	pushClient.On("deleteVariant", mock.Anything, "android", "myVariantId").Return(nil)
 
-	op.handleDeleteSecret(&bindingSecret)
+	op.handleDeleteSecret(context.Background(), &bindingSecret)
 
-	kubeHelper.AssertCalled(t, "updateSecret", mock.MatchedBy(func(secret *v1.Secret) bool {
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
 		// Annotation for Android should be deleted
 		annotationGood := reflect.DeepEqual(secret.Annotations, map[string]string{
 			"binding/ios": "toBeKept",
@@ -117,7 +170,13 @@ func TestConfigOperator_handleDeleteSecret_whenThereAre2Variants(t *testing.T) {
 		return annotationGood && secretConfigGood
 	}))
 
-	kubeHelper.AssertNotCalled(t, "deleteSecret", mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteSecret", mock.Anything, mock.Anything)
+
+	// The push status keeps the remaining iOS config
+	annotationHelper.AssertCalled(t, "setPushServiceStatus", mock.Anything, "myClientId", mock.MatchedBy(func(service v1alpha1.MobileClientService) bool {
+		return service.Type == "push" && string(service.Config) == "{\"ios\":{\"variantId\":\"yourVariantId\",\"pop\":\"cake\"}}"
+	}))
+	annotationHelper.AssertNotCalled(t, "removePushServiceStatus", mock.Anything, mock.Anything)
 }
 
 func TestConfigOperator_handleDeleteSecret_whenThereIs1Variant(t *testing.T) {
@@ -149,18 +208,23 @@ func TestConfigOperator_handleDeleteSecret_whenThereIs1Variant(t *testing.T) {
 		"binding/android": "toBeGone",
 	}
 
-	kubeHelper.On("findMobileClientConfig", "myClientId").Return(configSecret)
-	annotationHelper.On("removeAnnotationFromMobileClient", "myClientId", "android", "myServiceInstanceName").Once()
-	kubeHelper.On("deleteSecret", "mySecretName").Once()
-	pushClient.On("deleteVariant", "android", "myVariantId").Return(true)
+	// created before bindings were stored as PushVariants
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	annotationHelper.On("removeAnnotationFromMobileClient", mock.Anything, "myClientId", "android", "myServiceInstanceName").Once()
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName").Once()
+	pushClient.On("deleteVariant", mock.Anything, "android", "myVariantId").Return(nil)
 
-	op.handleDeleteSecret(&bindingSecret)
+	op.handleDeleteSecret(context.Background(), &bindingSecret)
 
-	kubeHelper.AssertCalled(t, "deleteSecret", "mySecretName")
-	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything)
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "mySecretName")
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeNormal, "VariantDeleted", "The android variant myVariantId has been deleted from UPS")
+	annotationHelper.AssertCalled(t, "removePushServiceStatus", mock.Anything, "myClientId")
+	annotationHelper.AssertNotCalled(t, "setPushServiceStatus", mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestConfigOperator_handleAddSecret_whenAndroid_andNoVariantExistsWithSameGoogleKey(t *testing.T) {
+func TestConfigOperator_syncPushVariant_whenAndroid_andNoVariantExistsWithSameGoogleKey(t *testing.T) {
 	setup()
 
 	bindingSecret := BindingSecret{
@@ -181,9 +245,9 @@ func TestConfigOperator_handleAddSecret_whenAndroid_andNoVariantExistsWithSameGo
 	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
 	pushClient.On("getApplicationId").Return("myPushApplicationId")
 	pushClient.On("getBaseUrl").Return("http://example.org")
-	pushClient.On("hasAndroidVariant", "myGoogleKey").Return(nil)
-	pushClient.On("getPushApplicationName").Return("myPushAppName", nil)
-	pushClient.On("createAndroidVariant", mock.Anything).Return(true, &AndroidVariant{
+	pushClient.On("hasAndroidVariant", mock.Anything, "myGoogleKey").Return(nil, nil)
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(&AndroidVariant{
 		ProjectNumber: "myProjectNumber",
 		GoogleKey:     "myGoogleKey",
 		Variant: Variant{
@@ -191,10 +255,11 @@ func TestConfigOperator_handleAddSecret_whenAndroid_andNoVariantExistsWithSameGo
 			VariantID: "myVariantId",
 			Secret:    "myVariantSecret",
 		},
-	})
+	}, nil)
 
 	// no existing client config
-	kubeHelper.On("findMobileClientConfig", "myClientId").Return(nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
 
 	configSecret := &v1.Secret{
 		Data: map[string][]byte{
@@ -203,18 +268,20 @@ func TestConfigOperator_handleAddSecret_whenAndroid_andNoVariantExistsWithSameGo
 		},
 	}
 	configSecret.Name = "mySecretName"
+	configSecret.Labels = map[string]string{
+		"pushApplicationId": "myPushApplicationId",
+	}
 	configSecret.Annotations = map[string]string{
 		"binding/ios": "toBeKept",
 	}
 
-	kubeHelper.On("createClientConfigSecret", "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret)
-	annotationHelper.On("addAnnotationToMobileClient", "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "android", "myVariantId", "myServiceInstanceName").Once()
-	kubeHelper.On("updateSecret", mock.Anything).Return(nil, nil)
-	kubeHelper.On("deleteSecret", "myBindingSecret").Once()
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "android", "myVariantId", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
 
-	op.handleAddSecret(&bindingSecret)
+	err := syncBindingSecret(&bindingSecret)
 
-	kubeHelper.AssertCalled(t, "updateSecret", mock.MatchedBy(func(secret *v1.Secret) bool {
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
 		// Annotation for Android should be deleted
 		if !reflect.DeepEqual(secret.Annotations, map[string]string{
 			"binding/android": "myServiceBindingId",
@@ -233,17 +300,36 @@ func TestConfigOperator_handleAddSecret_whenAndroid_andNoVariantExistsWithSameGo
 			return false;
 		}
 
+		// Config secrets without the finalizer get it on the next update
+		if !hasFinalizer(secret.ObjectMeta, "push.aerogear.org/delete-variants") {
+			return false
+		}
+
 		return true
 	}))
 
-	kubeHelper.AssertCalled(t, "deleteSecret", "myBindingSecret")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeNormal, "VariantCreated", "The android variant myVariantId has been created in UPS")
+	eventHelper.AssertCalled(t, "serviceBindingEvent", mock.Anything, "myServiceBindingId", v1.EventTypeNormal, "VariantCreated", "The android variant myVariantId has been created in UPS")
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeNormal, "ConfigSecretUpdated", mock.Anything)
+	annotationHelper.AssertCalled(t, "setPushServiceStatus", mock.Anything, "myClientId", v1alpha1.MobileClientService{
+		Id:      "myPushApplicationId",
+		Name:    "ups",
+		Type:    "push",
+		Url:     "http://example.org",
+		Config:  json.RawMessage("{\"android\":{\"senderId\":\"myProjectNumber\",\"variantId\":\"myVariantId\",\"variantSecret\":\"myVariantSecret\"},\"ios\":{\"variantId\":\"yourVariantId\",\"pop\":\"cake\"}}"),
+		Version: "1",
+	})
 
 	kubeHelper.AssertExpectations(t)
 	annotationHelper.AssertExpectations(t)
 }
 
 
-func TestConfigOperator_handleAddSecret_whenIOS(t *testing.T) {
+func TestConfigOperator_syncPushVariant_whenIOS(t *testing.T) {
 	setup()
 
 	bindingSecret := BindingSecret{
@@ -264,7 +350,7 @@ func TestConfigOperator_handleAddSecret_whenIOS(t *testing.T) {
 	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
 	pushClient.On("getApplicationId").Return("myPushApplicationId")
 	pushClient.On("getBaseUrl").Return("http://example.org")
-	pushClient.On("createIOSVariant", mock.Anything).Return(true, &IOSVariant{
+	pushClient.On("createIOSVariant", mock.Anything, mock.Anything).Return(&IOSVariant{
 		Certificate: []byte("myCertificate"),
 		Passphrase:     "myPassphrase",
 		Variant: Variant{
@@ -272,11 +358,12 @@ func TestConfigOperator_handleAddSecret_whenIOS(t *testing.T) {
 			VariantID: "myVariantId",
 			Secret:    "myVariantSecret",
 		},
-	})
-	pushClient.On("getPushApplicationName").Return("myPushAppName", nil)
+	}, nil)
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
 
 	// no existing client config
-	kubeHelper.On("findMobileClientConfig", "myClientId").Return(nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
 
 	configSecret := &v1.Secret{
 		Data: map[string][]byte{
@@ -289,14 +376,13 @@ func TestConfigOperator_handleAddSecret_whenIOS(t *testing.T) {
 		"binding/android": "toBeKept",
 	}
 
-	kubeHelper.On("createClientConfigSecret", "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret)
-	annotationHelper.On("addAnnotationToMobileClient", "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "ios", "myVariantId", "myServiceInstanceName").Once()
-	kubeHelper.On("updateSecret", mock.Anything).Return(nil, nil)
-	kubeHelper.On("deleteSecret", "myBindingSecret").Once()
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "ios", "myVariantId", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
 
-	op.handleAddSecret(&bindingSecret)
+	err := syncBindingSecret(&bindingSecret)
 
-	kubeHelper.AssertCalled(t, "updateSecret", mock.MatchedBy(func(secret *v1.Secret) bool {
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
 		// Annotation for Android should be deleted
 		if !reflect.DeepEqual(secret.Annotations, map[string]string{
 			"binding/android": "toBeKept",
@@ -318,8 +404,427 @@ func TestConfigOperator_handleAddSecret_whenIOS(t *testing.T) {
 		return true
 	}))
 
-	kubeHelper.AssertCalled(t, "deleteSecret", "myBindingSecret")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 
 	kubeHelper.AssertExpectations(t)
 	annotationHelper.AssertExpectations(t)
 }
+
+func TestConfigOperator_syncPushVariant_whenIOSToken(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("IOS"),
+			"clientId":            []byte("myClientId"),
+			"privateKey":          []byte("myPrivateKey"),
+			"keyId":               []byte("myKeyId"),
+			"teamId":              []byte("myTeamId"),
+			"bundleId":            []byte("org.example.app"),
+			"isProduction":        []byte("true"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("createIOSTokenVariant", mock.Anything, mock.Anything).Return(&IOSTokenVariant{
+		Variant: Variant{
+			Name:      "myIOSVariant",
+			VariantID: "myVariantId",
+			Secret:    "myVariantSecret",
+		},
+	}, nil)
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"config": []byte("{}"),
+		},
+	}
+	configSecret.Name = "mySecretName"
+
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "ios", "myVariantId", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := syncBindingSecret(&bindingSecret)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertCalled(t, "createIOSTokenVariant", mock.Anything, mock.MatchedBy(func(variant *IOSTokenVariant) bool {
+		return variant.PrivateKey == "myPrivateKey" &&
+			variant.KeyID == "myKeyId" &&
+			variant.TeamID == "myTeamId" &&
+			variant.BundleID == "org.example.app" &&
+			variant.Production
+	}))
+	pushClient.AssertNotCalled(t, "createIOSVariant", mock.Anything, mock.Anything)
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return string(secret.Data["config"]) == "{\"ios\":{\"variantId\":\"myVariantId\",\"variantSecret\":\"myVariantSecret\"}}"
+	}))
+
+	annotationHelper.AssertExpectations(t)
+}
+
+func TestConfigOperator_syncPushVariant_whenIOSSwitchesFromCertificateToToken(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("IOS"),
+			"clientId":            []byte("myClientId"),
+			"privateKey":          []byte("myPrivateKey"),
+			"keyId":               []byte("myKeyId"),
+			"teamId":              []byte("myTeamId"),
+			"bundleId":            []byte("org.example.app"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"config": []byte("{\"ios\":{\"variantId\":\"myCertificateVariantId\",\"variantSecret\":\"myVariantSecret\"}}"),
+		},
+	}
+	configSecret.Name = "mySecretName"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", mock.Anything, mock.Anything, mock.Anything, "ios", "myTokenVariantId", "myServiceInstanceName")
+
+	// the certificate variant is no token variant
+	pushClient.On("updateIOSTokenVariant", mock.Anything, mock.Anything).Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	pushClient.On("createIOSTokenVariant", mock.Anything, mock.Anything).Return(&IOSTokenVariant{
+		Variant: Variant{Name: "myClientId", VariantID: "myTokenVariantId", Secret: "myNewVariantSecret"},
+	}, nil)
+	pushClient.On("deleteVariant", mock.Anything, "ios", "myCertificateVariantId").Return(nil)
+
+	err := syncBindingSecret(&bindingSecret)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return string(secret.Data["config"]) == "{\"ios\":{\"variantId\":\"myTokenVariantId\",\"variantSecret\":\"myNewVariantSecret\"}}"
+	}))
+	// the certificate variant would keep the old credentials
+	pushClient.AssertCalled(t, "deleteVariant", mock.Anything, "ios", "myCertificateVariantId")
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, "myTokenVariantId")
+}
+
+func TestConfigOperator_syncPushVariant_whenWebPush(t *testing.T) {
+	setup()
+	publicKey, privateKey, _ := generateVapidKeys()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("WebPush"),
+			"clientId":            []byte("myClientId"),
+			"vapidSubject":        []byte("mailto:admin@example.org"),
+			"vapidPublicKey":      []byte(publicKey),
+			"vapidPrivateKey":     []byte(privateKey),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("createWebPushVariant", mock.Anything, mock.Anything).Return(&WebPushVariant{
+		PublicKey: "myPublicKey",
+		Variant: Variant{
+			Name:      "myWebPushVariant",
+			VariantID: "myVariantId",
+			Secret:    "myVariantSecret",
+		},
+	}, nil)
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"config": []byte("{}"),
+		},
+	}
+	configSecret.Name = "mySecretName"
+
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "webpush", "myVariantId", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := syncBindingSecret(&bindingSecret)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertCalled(t, "createWebPushVariant", mock.Anything, mock.MatchedBy(func(variant *WebPushVariant) bool {
+		return variant.Alias == "mailto:admin@example.org" && variant.PublicKey == publicKey && variant.PrivateKey == privateKey
+	}))
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return secret.Annotations["binding/webpush"] == "myServiceBindingId" &&
+			string(secret.Data["config"]) == "{\"webpush\":{\"appServerKey\":\"myPublicKey\",\"variantId\":\"myVariantId\",\"variantSecret\":\"myVariantSecret\"}}"
+	}))
+
+	annotationHelper.AssertExpectations(t)
+}
+
+func TestConfigOperator_syncPushVariant_whenUPSRejectsTheVariant(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte(""),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(nil, newUpsResponseError(400, []byte(`{"googleKey":"may not be null"}`)))
+
+	err := syncBindingSecret(&bindingSecret)
+
+	// not retried until the PushVariant or its credentials change
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubeHelper.AssertCalled(t, "updatePushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return strings.Contains(pushVariant.Status.LastError, "googleKey") &&
+			pushVariant.Status.Conditions[0].Status == v1.ConditionFalse
+	}))
+	kubeHelper.AssertNotCalled(t, "createClientConfigSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+	annotationHelper.AssertNotCalled(t, "addAnnotationToMobileClient", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// the UPS reason is shown to the developer
+	for _, method := range []string{"mobileClientEvent", "serviceBindingEvent"} {
+		eventHelper.AssertCalled(t, method, mock.Anything, mock.Anything, v1.EventTypeWarning, "VariantCreationFailed", mock.MatchedBy(func(message string) bool {
+			return strings.Contains(message, "ValidationFailed") && strings.Contains(message, "googleKey")
+		}))
+	}
+}
+
+func TestConfigOperator_syncPushVariant_whenUPSIsUnavailable(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":  []byte("Android"),
+			"clientId": []byte("myClientId"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(nil, errUpsCircuitOpen)
+
+	err := syncBindingSecret(&bindingSecret)
+
+	// the PushVariant is synced again
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected the sync to be retried but got %v", err)
+	}
+}
+
+func TestConfigOperator_syncPushVariant_whenKubernetesFails(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, errors.New("the server is currently unable to handle the request"))
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(&AndroidVariant{Variant: Variant{VariantID: "myVariantId"}}, nil)
+
+	err := syncBindingSecret(&bindingSecret)
+
+	// the PushVariant is synced again, so that the variant gets its config secret
+	if err == nil {
+		t.Errorf("expected the sync to be retried")
+	}
+}
+
+func TestConfigOperator_syncPushVariant_usesOnePushClient(t *testing.T) {
+	setup()
+
+	// the push application cannot be looked up anymore after the variant has been created
+	pushClientProvider = new(MockUpsClientProvider)
+	pushClientProvider.On("getPushClient", mock.Anything).Return(pushClient).Once()
+	pushClientProvider.On("getPushClient", mock.Anything).Return(nil)
+	op.pushClientProvider = pushClientProvider
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	configSecret := &v1.Secret{Data: map[string][]byte{"config": []byte("{}")}}
+	configSecret.Name = "mySecretName"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(&AndroidVariant{Variant: Variant{VariantID: "myVariantId"}}, nil)
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	err := syncBindingSecret(&bindingSecret)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubeHelper.AssertCalled(t, "createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId")
+}
+
+func TestConfigOperator_syncPushVariant_whenAndroidVariantExists(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myNewGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myNewServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+			"config":              []byte("{\"android\":{\"senderId\":\"myProjectNumber\",\"variantId\":\"myVariantId\",\"variantSecret\":\"myVariantSecret\"}}"),
+		},
+	}
+	configSecret.Name = "mySecretName"
+	configSecret.Annotations = map[string]string{
+		"binding/android": "myServiceBindingId",
+	}
+
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	pushClient.On("updateAndroidVariant", mock.Anything, mock.Anything).Return(func(ctx context.Context, variant *AndroidVariant) *AndroidVariant {
+		return variant
+	}, nil)
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "android", "myVariantId", "myServiceInstanceName").Once()
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := syncBindingSecret(&bindingSecret)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertCalled(t, "updateAndroidVariant", mock.Anything, mock.MatchedBy(func(variant *AndroidVariant) bool {
+		return variant.VariantID == "myVariantId" && variant.Secret == "myVariantSecret" && variant.GoogleKey == "myNewGoogleKey"
+	}))
+	pushClient.AssertNotCalled(t, "createAndroidVariant", mock.Anything, mock.Anything)
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return secret.Annotations["binding/android"] == "myNewServiceBindingId" &&
+			string(secret.Data["config"]) == "{\"android\":{\"senderId\":\"myProjectNumber\",\"variantId\":\"myVariantId\",\"variantSecret\":\"myVariantSecret\"}}"
+	}))
+	kubeHelper.AssertNotCalled(t, "createClientConfigSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	annotationHelper.AssertExpectations(t)
+}
+
+func TestConfigOperator_handleDeleteSecret_whenVariantBelongsToNewerBinding(t *testing.T) {
+	setup()
+
+	bindingSecret := BindingSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ServiceBinding"},
+			},
+		},
+		Data: map[string][]byte{
+			"appType":          []byte("android"),
+			"clientId":         []byte("myClientId"),
+			"serviceBindingId": []byte("myOldServiceBindingId"),
+		},
+	}
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+			"config":              []byte("{\"android\":{\"variantId\":\"myVariantId\"}}"),
+		},
+	}
+	configSecret.Annotations = map[string]string{
+		"binding/android": "myNewServiceBindingId",
+	}
+
+	// created before bindings were stored as PushVariants
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+
+	op.handleDeleteSecret(context.Background(), &bindingSecret)
+
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteSecret", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+}
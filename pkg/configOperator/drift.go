@@ -0,0 +1,108 @@
+package configOperator
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// Counts in how many polls in a row an ID has been seen
+type pollStreaks struct {
+	streaks map[string]int
+}
+
+func newPollStreaks() *pollStreaks {
+	streaks := new(pollStreaks)
+
+	streaks.streaks = make(map[string]int)
+
+	return streaks
+}
+
+// Records the IDs seen by a poll. IDs that have not been seen start over.
+func (streaks *pollStreaks) record(ids []string) {
+	updated := make(map[string]int, len(ids))
+	for _, id := range ids {
+		updated[id] = streaks.streaks[id] + 1
+	}
+	streaks.streaks = updated
+}
+
+func (streaks *pollStreaks) get(id string) int {
+	return streaks.streaks[id]
+}
+
+// Deletes the service bindings of variants that are missing from the variants listed by UPS. An
+// incomplete list, e.g. while UPS restarts, must not remove bindings: a variant only counts as
+// deleted once a direct lookup returns not found or, if UPS cannot tell, once it has been missing
+// in driftMissedPolls polls in a row. Protected bindings are kept, and a poll deletes at most
+// driftMaxDeletions bindings, the others are deleted by the next polls.
+func (op ConfigOperator) deleteBindingsOfDeletedVariants(ctx context.Context, pushClient UpsClient, missing []VariantServiceBindingMapping) {
+	var deletedVariants []VariantServiceBindingMapping
+	var deletedIds []string
+	confirmed := make(map[string]bool)
+	for _, clientConfig := range missing {
+		exists, err := op.variantExists(ctx, pushClient, clientConfig)
+		if err != nil {
+			log.Printf("Variant %s is missing from the variants of UPS and cannot be looked up: %s", clientConfig.VariantId, err.Error())
+		} else if exists {
+			log.Printf("Variant %s is missing from the variants of UPS but still exists", clientConfig.VariantId)
+			continue
+		} else {
+			confirmed[clientConfig.VariantId] = true
+		}
+		deletedVariants = append(deletedVariants, clientConfig)
+		deletedIds = append(deletedIds, clientConfig.VariantId)
+	}
+	op.missingVariants.record(deletedIds)
+
+	deletions := 0
+	for _, clientConfig := range deletedVariants {
+		polls := op.missingVariants.get(clientConfig.VariantId)
+		if !confirmed[clientConfig.VariantId] && polls < op.driftMissedPolls {
+			log.Printf("Variant %s has been missing from UPS in %d of %d polls, keeping service binding %s for now", clientConfig.VariantId, polls, op.driftMissedPolls, clientConfig.ServiceBindingId)
+			driftSkippedDeletions.WithLabelValues(driftSkipUnconfirmed).Inc()
+			continue
+		}
+		if op.driftMaxDeletions > 0 && deletions >= op.driftMaxDeletions {
+			log.Printf("This poll has deleted %d service bindings already, keeping service binding %s of variant %s until the next poll", deletions, clientConfig.ServiceBindingId, clientConfig.VariantId)
+			driftSkippedDeletions.WithLabelValues(driftSkipLimit).Inc()
+			continue
+		}
+
+		log.Printf("Variant %s of client `%s` has been deleted in UPS, deleting service binding %s", clientConfig.VariantId, clientConfig.ClientId, clientConfig.ServiceBindingId)
+		deletions++
+		deleted, err := op.handleDeleteServiceBinding(ctx, clientConfig.ServiceBindingId)
+		if err != nil {
+			log.Printf("Error deleting service binding instance with id %s\n%s", clientConfig.ServiceBindingId, err.Error())
+			continue
+		}
+		if !deleted {
+			log.Printf("Service binding %s is protected, keeping it", clientConfig.ServiceBindingId)
+			driftSkippedDeletions.WithLabelValues(driftSkipProtected).Inc()
+			continue
+		}
+
+		driftDeletedVariants.Inc()
+		op.recordEvent(ctx, clientConfig.ClientId, clientConfig.ServiceBindingId, v1.EventTypeWarning, eventReasonDriftBindingDeleted,
+			fmt.Sprintf("The variant %s has been deleted in UPS, deleting the service binding", clientConfig.VariantId))
+	}
+}
+
+// Looks a variant up by its ID. Returns an error if UPS cannot tell whether the variant exists,
+// e.g. because UPS is unavailable or the platform is unknown.
+func (op ConfigOperator) variantExists(ctx context.Context, pushClient UpsClient, clientConfig VariantServiceBindingMapping) (bool, error) {
+	platform := op.platforms.get(clientConfig.Platform)
+	if platform == nil {
+		return false, errors.Errorf("unsupported platform `%s`", clientConfig.Platform)
+	}
+
+	variant, err := platform.getVariant(ctx, pushClient, clientConfig.VariantId)
+	if err != nil {
+		return false, err
+	}
+	return variant != nil, nil
+}
@@ -0,0 +1,94 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newMissingVariant(variantId string, bindingId string) VariantServiceBindingMapping {
+	return VariantServiceBindingMapping{
+		VariantId:        variantId,
+		ServiceBindingId: bindingId,
+		ClientId:         "myClientId",
+		Platform:         "android",
+	}
+}
+
+func newServiceBinding(name string, annotations map[string]string) *v1beta1.ServiceBinding {
+	return &v1beta1.ServiceBinding{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func TestConfigOperator_deleteBindingsOfDeletedVariants_waitsForSeveralPollsWhileUPSCannotTell(t *testing.T) {
+	setup()
+
+	pushClient.On("getVariant", mock.Anything, "android", "myVariantId").Return(nil, errUpsCircuitOpen)
+	kubeHelper.On("getServiceBindingByID", mock.Anything, "myBindingId").Return(newServiceBinding("myBinding", nil), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, "myBinding").Return(nil)
+
+	missing := []VariantServiceBindingMapping{newMissingVariant("myVariantId", "myBindingId")}
+	for i := 1; i < op.driftMissedPolls; i++ {
+		op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, missing)
+	}
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, mock.Anything)
+
+	op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, missing)
+	kubeHelper.AssertCalled(t, "deleteServiceBinding", mock.Anything, "myBinding")
+}
+
+func TestConfigOperator_deleteBindingsOfDeletedVariants_keepsBindingsOfExistingVariants(t *testing.T) {
+	setup()
+
+	// the variant is missing from the list, e.g. because UPS returned an incomplete list
+	pushClient.On("getVariant", mock.Anything, "android", "myVariantId").Return(&Variant{VariantID: "myVariantId"}, nil)
+
+	missing := []VariantServiceBindingMapping{newMissingVariant("myVariantId", "myBindingId")}
+	for i := 0; i <= op.driftMissedPolls; i++ {
+		op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, missing)
+	}
+
+	kubeHelper.AssertNotCalled(t, "getServiceBindingByID", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_deleteBindingsOfDeletedVariants_keepsProtectedBindings(t *testing.T) {
+	setup()
+
+	pushClient.On("getVariant", mock.Anything, "android", mock.Anything).Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	kubeHelper.On("getServiceBindingByID", mock.Anything, "myProtectedBindingId").Return(newServiceBinding("myProtectedBinding", map[string]string{"push.aerogear.org/protected": "true"}), nil)
+	kubeHelper.On("getServiceBindingByID", mock.Anything, "myBindingId").Return(newServiceBinding("myBinding", map[string]string{"push.aerogear.org/protected": "false"}), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, "myBinding").Return(nil)
+
+	op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, []VariantServiceBindingMapping{
+		newMissingVariant("myProtectedVariantId", "myProtectedBindingId"),
+		newMissingVariant("myVariantId", "myBindingId"),
+	})
+
+	kubeHelper.AssertCalled(t, "deleteServiceBinding", mock.Anything, "myBinding")
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, "myProtectedBinding")
+	eventHelper.AssertNumberOfCalls(t, "mobileClientEvent", 1)
+}
+
+func TestConfigOperator_deleteBindingsOfDeletedVariants_deletesAtMostTheLimitPerPoll(t *testing.T) {
+	setup()
+	op.driftMaxDeletions = 2
+
+	pushClient.On("getVariant", mock.Anything, "android", mock.Anything).Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	kubeHelper.On("getServiceBindingByID", mock.Anything, mock.Anything).Return(newServiceBinding("myBinding", nil), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, "myBinding").Return(nil)
+
+	missing := []VariantServiceBindingMapping{
+		newMissingVariant("myVariantId1", "myBindingId1"),
+		newMissingVariant("myVariantId2", "myBindingId2"),
+		newMissingVariant("myVariantId3", "myBindingId3"),
+	}
+	op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, missing)
+	kubeHelper.AssertNumberOfCalls(t, "deleteServiceBinding", 2)
+	kubeHelper.AssertNotCalled(t, "getServiceBindingByID", mock.Anything, "myBindingId3")
+
+	op.deleteBindingsOfDeletedVariants(context.Background(), pushClient, missing[2:])
+	kubeHelper.AssertCalled(t, "getServiceBindingByID", mock.Anything, "myBindingId3")
+}
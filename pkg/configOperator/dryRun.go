@@ -0,0 +1,350 @@
+package configOperator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"k8s.io/api/core/v1"
+
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+// Targets of the mutations recorded in dry-run mode
+const (
+	dryRunTargetUps        = "ups"
+	dryRunTargetKubernetes = "kubernetes"
+)
+
+// A mutation of UPS or Kubernetes that has been recorded instead of made. Credentials are
+// never part of the details.
+type DryRunAction struct {
+	Time time.Time `json:"time"`
+	// `ups` or `kubernetes`
+	Target string `json:"target"`
+	// Method of the client or helper that would have been called, e.g. `createAndroidVariant`
+	Operation string `json:"operation"`
+	// Kind of the changed object, e.g. `variant` or `secret`
+	Resource string            `json:"resource"`
+	Name     string            `json:"name"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+// Logs, counts and keeps the mutations the operator would make in dry-run mode. Only the
+// latest actions are kept, the oldest are dropped once the limit is reached.
+type DryRunRecorder struct {
+	mutex   sync.Mutex
+	actions []DryRunAction
+	limit   int
+}
+
+func NewDryRunRecorder(limit int) *DryRunRecorder {
+	recorder := new(DryRunRecorder)
+
+	recorder.limit = limit
+
+	return recorder
+}
+
+func (recorder *DryRunRecorder) record(target string, operation string, resource string, name string, details map[string]string) {
+	action := DryRunAction{
+		Time:      time.Now(),
+		Target:    target,
+		Operation: operation,
+		Resource:  resource,
+		Name:      name,
+		Details:   details,
+	}
+
+	if line, err := json.Marshal(action); err == nil {
+		log.Printf("Dry run, skipping %s", line)
+	}
+	dryRunActions.WithLabelValues(target, operation).Inc()
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.actions = append(recorder.actions, action)
+	if recorder.limit > 0 && len(recorder.actions) > recorder.limit {
+		recorder.actions = recorder.actions[len(recorder.actions)-recorder.limit:]
+	}
+}
+
+// Returns the recorded actions, the oldest first
+func (recorder *DryRunRecorder) Actions() []DryRunAction {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	actions := make([]DryRunAction, len(recorder.actions))
+	copy(actions, recorder.actions)
+	return actions
+}
+
+// Serves the recorded actions as a JSON array
+func (recorder *DryRunRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recorder.Actions())
+}
+
+// Variants and applications created in dry-run mode get a placeholder ID, so that the
+// operator can go on with what it would do next
+func newDryRunId() string {
+	return "dry-run-" + getRandomIdentifier(8)
+}
+
+func variantDetails(platform string, variant Variant) map[string]string {
+	return map[string]string{"platform": platform, "variantId": variant.VariantID}
+}
+
+// Provides push clients that record their mutations instead of sending them
+type DryRunUpsClientProvider struct {
+	UpsClientProvider
+	recorder *DryRunRecorder
+}
+
+func NewDryRunUpsClientProvider(provider UpsClientProvider, recorder *DryRunRecorder) *DryRunUpsClientProvider {
+	dryRunProvider := new(DryRunUpsClientProvider)
+
+	dryRunProvider.UpsClientProvider = provider
+	dryRunProvider.recorder = recorder
+
+	return dryRunProvider
+}
+
+func (provider DryRunUpsClientProvider) getPushClient(ctx context.Context) UpsClient {
+	return newDryRunUpsClient(provider.UpsClientProvider.getPushClient(ctx), provider.recorder)
+}
+
+func (provider DryRunUpsClientProvider) getAdminClient(ctx context.Context) UpsClient {
+	return newDryRunUpsClient(provider.UpsClientProvider.getAdminClient(ctx), provider.recorder)
+}
+
+// Reads from UPS and records the changes. Created variants and applications are returned
+// with a placeholder ID, updated ones are returned unchanged.
+type dryRunUpsClient struct {
+	UpsClient
+	recorder *DryRunRecorder
+}
+
+// Returns nil if there is no client, like the provider does
+func newDryRunUpsClient(client UpsClient, recorder *DryRunRecorder) UpsClient {
+	if client == nil {
+		return nil
+	}
+
+	dryRunClient := new(dryRunUpsClient)
+
+	dryRunClient.UpsClient = client
+	dryRunClient.recorder = recorder
+
+	return dryRunClient
+}
+
+func (client dryRunUpsClient) createAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
+	created := *variant
+	created.VariantID = newDryRunId()
+	client.recorder.record(dryRunTargetUps, "createAndroidVariant", "variant", variant.Name, variantDetails("android", created.Variant))
+	return &created, nil
+}
+
+func (client dryRunUpsClient) createIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	created := *variant
+	created.VariantID = newDryRunId()
+	client.recorder.record(dryRunTargetUps, "createIOSVariant", "variant", variant.Name, variantDetails("ios", created.Variant))
+	return &created, nil
+}
+
+func (client dryRunUpsClient) createIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	created := *variant
+	created.VariantID = newDryRunId()
+	client.recorder.record(dryRunTargetUps, "createIOSTokenVariant", "variant", variant.Name, variantDetails("ios_token", created.Variant))
+	return &created, nil
+}
+
+func (client dryRunUpsClient) createWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	created := *variant
+	created.VariantID = newDryRunId()
+	client.recorder.record(dryRunTargetUps, "createWebPushVariant", "variant", variant.Name, variantDetails("web_push", created.Variant))
+	return &created, nil
+}
+
+func (client dryRunUpsClient) updateAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
+	client.recorder.record(dryRunTargetUps, "updateAndroidVariant", "variant", variant.Name, variantDetails("android", variant.Variant))
+	return variant, nil
+}
+
+func (client dryRunUpsClient) updateIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	client.recorder.record(dryRunTargetUps, "updateIOSVariant", "variant", variant.Name, variantDetails("ios", variant.Variant))
+	return variant, nil
+}
+
+func (client dryRunUpsClient) updateIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	client.recorder.record(dryRunTargetUps, "updateIOSTokenVariant", "variant", variant.Name, variantDetails("ios_token", variant.Variant))
+	return variant, nil
+}
+
+func (client dryRunUpsClient) updateWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	client.recorder.record(dryRunTargetUps, "updateWebPushVariant", "variant", variant.Name, variantDetails("web_push", variant.Variant))
+	return variant, nil
+}
+
+func (client dryRunUpsClient) deleteVariant(ctx context.Context, platform string, variantId string) error {
+	client.recorder.record(dryRunTargetUps, "deleteVariant", "variant", variantId, map[string]string{"platform": platform, "variantId": variantId})
+	return nil
+}
+
+func (client dryRunUpsClient) createApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	created := *application
+	created.ApplicationId = newDryRunId()
+	client.recorder.record(dryRunTargetUps, "createApplication", "application", application.Name, map[string]string{"applicationId": created.ApplicationId})
+	return &created, nil
+}
+
+func (client dryRunUpsClient) updateApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	client.recorder.record(dryRunTargetUps, "updateApplication", "application", application.Name, map[string]string{"applicationId": application.ApplicationId})
+	return application, nil
+}
+
+func (client dryRunUpsClient) deleteApplication(ctx context.Context, applicationId string) error {
+	client.recorder.record(dryRunTargetUps, "deleteApplication", "application", applicationId, nil)
+	return nil
+}
+
+// Reads from Kubernetes and records the changes. Created and updated objects are returned
+// as they would have been sent.
+type DryRunKubeHelper struct {
+	KubeHelper
+	recorder *DryRunRecorder
+}
+
+func NewDryRunKubeHelper(helper KubeHelper, recorder *DryRunRecorder) *DryRunKubeHelper {
+	dryRunHelper := new(DryRunKubeHelper)
+
+	dryRunHelper.KubeHelper = helper
+	dryRunHelper.recorder = recorder
+
+	return dryRunHelper
+}
+
+// Whether the operator runs in dry-run mode, in which PushVariants are recorded instead of stored
+func isDryRun(helper KubeHelper) bool {
+	_, ok := helper.(*DryRunKubeHelper)
+	return ok
+}
+
+func (helper DryRunKubeHelper) deleteSecret(ctx context.Context, name string) {
+	helper.recorder.record(dryRunTargetKubernetes, "deleteSecret", "secret", name, nil)
+}
+
+func (helper DryRunKubeHelper) createClientConfigSecret(ctx context.Context, clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) (*v1.Secret, error) {
+	mobileClient, err := helper.getMobileClient(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+	secret := newClientConfigSecret(clientId, serviceInstanceName, serviceInstanceId, pushAppId, mobileClient)
+	helper.recorder.record(dryRunTargetKubernetes, "createClientConfigSecret", "secret", secret.Name, map[string]string{"clientId": clientId})
+	return secret, nil
+}
+
+func (helper DryRunKubeHelper) updateSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	helper.recorder.record(dryRunTargetKubernetes, "updateSecret", "secret", secret.Name, nil)
+	return secret, nil
+}
+
+func (helper DryRunKubeHelper) createSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	helper.recorder.record(dryRunTargetKubernetes, "createSecret", "secret", secret.Name, nil)
+	return secret, nil
+}
+
+func (helper DryRunKubeHelper) deleteServiceBinding(ctx context.Context, bindingName string) error {
+	helper.recorder.record(dryRunTargetKubernetes, "deleteServiceBinding", "servicebinding", bindingName, nil)
+	return nil
+}
+
+func (helper DryRunKubeHelper) createPushVariant(ctx context.Context, pushVariant *push.PushVariant) (*push.PushVariant, error) {
+	helper.recorder.record(dryRunTargetKubernetes, "createPushVariant", "pushvariant", pushVariant.Name, nil)
+	return pushVariant, nil
+}
+
+func (helper DryRunKubeHelper) updatePushVariant(ctx context.Context, pushVariant *push.PushVariant) (*push.PushVariant, error) {
+	helper.recorder.record(dryRunTargetKubernetes, "updatePushVariant", "pushvariant", pushVariant.Name, map[string]string{"variantId": pushVariant.Status.VariantId})
+	return pushVariant, nil
+}
+
+func (helper DryRunKubeHelper) deletePushVariant(ctx context.Context, name string) error {
+	helper.recorder.record(dryRunTargetKubernetes, "deletePushVariant", "pushvariant", name, nil)
+	return nil
+}
+
+func (helper DryRunKubeHelper) updatePushApplication(ctx context.Context, pushApplication *push.PushApplication) (*push.PushApplication, error) {
+	helper.recorder.record(dryRunTargetKubernetes, "updatePushApplication", "pushapplication", pushApplication.Name, nil)
+	return pushApplication, nil
+}
+
+// Records the changes to the annotations and the status of mobile clients
+type DryRunAnnotationHelper struct {
+	recorder *DryRunRecorder
+}
+
+func NewDryRunAnnotationHelper(recorder *DryRunRecorder) *DryRunAnnotationHelper {
+	helper := new(DryRunAnnotationHelper)
+
+	helper.recorder = recorder
+
+	return helper
+}
+
+func (helper DryRunAnnotationHelper) addAnnotationToMobileClient(ctx context.Context, clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantId string, serviceInstanceName string) {
+	helper.recorder.record(dryRunTargetKubernetes, "addAnnotationToMobileClient", "mobileclient", clientId, map[string]string{
+		"platform":            appType,
+		"variantId":           variantId,
+		"serviceInstanceName": serviceInstanceName,
+	})
+}
+
+func (helper DryRunAnnotationHelper) removeAnnotationFromMobileClient(ctx context.Context, clientId string, appType string, serviceInstanceName string) {
+	helper.recorder.record(dryRunTargetKubernetes, "removeAnnotationFromMobileClient", "mobileclient", clientId, map[string]string{
+		"platform":            appType,
+		"serviceInstanceName": serviceInstanceName,
+	})
+}
+
+func (helper DryRunAnnotationHelper) setPushServiceStatus(ctx context.Context, clientId string, service v1alpha1.MobileClientService) {
+	helper.recorder.record(dryRunTargetKubernetes, "setPushServiceStatus", "mobileclient", clientId, nil)
+}
+
+func (helper DryRunAnnotationHelper) removePushServiceStatus(ctx context.Context, clientId string) {
+	helper.recorder.record(dryRunTargetKubernetes, "removePushServiceStatus", "mobileclient", clientId, nil)
+}
+
+// Records events instead of sending them, a dry run must not report changes it didn't make
+type DryRunEventHelper struct {
+	recorder *DryRunRecorder
+}
+
+func NewDryRunEventHelper(recorder *DryRunRecorder) *DryRunEventHelper {
+	helper := new(DryRunEventHelper)
+
+	helper.recorder = recorder
+
+	return helper
+}
+
+func (helper DryRunEventHelper) mobileClientEvent(ctx context.Context, clientId string, eventType string, reason string, message string) {
+	helper.recorder.record(dryRunTargetKubernetes, "mobileClientEvent", "mobileclient", clientId, map[string]string{
+		"type":    eventType,
+		"reason":  reason,
+		"message": message,
+	})
+}
+
+func (helper DryRunEventHelper) serviceBindingEvent(ctx context.Context, bindingId string, eventType string, reason string, message string) {
+	helper.recorder.record(dryRunTargetKubernetes, "serviceBindingEvent", "servicebinding", bindingId, map[string]string{
+		"type":    eventType,
+		"reason":  reason,
+		"message": message,
+	})
+}
@@ -0,0 +1,160 @@
+package configOperator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+func TestDryRunUpsClient_recordsChangesAndReads(t *testing.T) {
+	setup()
+	recorder := NewDryRunRecorder(10)
+	pushClient.On("getVariantsForPlatform", mock.Anything, "android").Return([]Variant{{VariantID: "myVariantId"}}, nil)
+
+	client := NewDryRunUpsClientProvider(pushClientProvider, recorder).getPushClient(context.Background())
+
+	variants, err := client.getVariantsForPlatform(context.Background(), "android")
+	if err != nil || len(variants) != 1 {
+		t.Errorf("expected the variants of UPS but got %v, %v", variants, err)
+	}
+
+	created, err := client.createAndroidVariant(context.Background(), &AndroidVariant{GoogleKey: "myGoogleKey", Variant: Variant{Name: "myClientId"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if created.VariantID == "" {
+		t.Errorf("expected the created variant to get a placeholder ID")
+	}
+	client.deleteVariant(context.Background(), "android", "myVariantId")
+
+	pushClient.AssertNotCalled(t, "createAndroidVariant", mock.Anything, mock.Anything)
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+
+	actions := recorder.Actions()
+	if len(actions) != 2 || actions[0].Operation != "createAndroidVariant" || actions[1].Operation != "deleteVariant" {
+		t.Fatalf("expected the creation and the deletion to be recorded but got %v", actions)
+	}
+	if actions[0].Target != "ups" || actions[0].Name != "myClientId" || actions[0].Details["variantId"] != created.VariantID {
+		t.Errorf("unexpected action %v", actions[0])
+	}
+}
+
+func TestDryRunUpsClientProvider_returnsNilWithoutPushClient(t *testing.T) {
+	provider := new(MockUpsClientProvider)
+	provider.On("getPushClient", mock.Anything).Return(nil)
+
+	client := NewDryRunUpsClientProvider(provider, NewDryRunRecorder(10)).getPushClient(context.Background())
+	if client != nil {
+		t.Errorf("expected no push client but got %v", client)
+	}
+}
+
+func TestDryRunKubeHelper_recordsChanges(t *testing.T) {
+	setup()
+	recorder := NewDryRunRecorder(10)
+	helper := NewDryRunKubeHelper(kubeHelper, recorder)
+
+	configSecret, err := helper.createClientConfigSecret(context.Background(), "myClientId", "myServiceInstanceName", "myServiceInstanceId", "myPushAppId")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if string(configSecret.Data["config"]) != "{}" || configSecret.Labels["clientId"] != "myClientId" {
+		t.Errorf("expected the config secret that would be created but got %v", configSecret)
+	}
+	helper.updateSecret(context.Background(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "mySecretName"}})
+	helper.deleteSecret(context.Background(), "mySecretName")
+	helper.deleteServiceBinding(context.Background(), "myBinding")
+
+	kubeHelper.AssertNotCalled(t, "createClientConfigSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteSecret", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, mock.Anything)
+
+	var operations []string
+	for _, action := range recorder.Actions() {
+		operations = append(operations, action.Operation)
+	}
+	expected := []string{"createClientConfigSecret", "updateSecret", "deleteSecret", "deleteServiceBinding"}
+	if len(operations) != len(expected) {
+		t.Fatalf("expected %v to be recorded but got %v", expected, operations)
+	}
+	for i := range expected {
+		if operations[i] != expected[i] {
+			t.Errorf("expected %v to be recorded but got %v", expected, operations)
+		}
+	}
+}
+
+func TestDryRunRecorder_keepsLatestActions(t *testing.T) {
+	recorder := NewDryRunRecorder(2)
+	recorder.record(dryRunTargetKubernetes, "deleteSecret", "secret", "first", nil)
+	recorder.record(dryRunTargetKubernetes, "deleteSecret", "secret", "second", nil)
+	recorder.record(dryRunTargetKubernetes, "deleteSecret", "secret", "third", nil)
+
+	response := httptest.NewRecorder()
+	recorder.ServeHTTP(response, httptest.NewRequest("GET", "/dry-run", nil))
+
+	var actions []DryRunAction
+	if err := json.Unmarshal(response.Body.Bytes(), &actions); err != nil {
+		t.Fatalf("expected a JSON array but got %s", response.Body.String())
+	}
+	if len(actions) != 2 || actions[0].Name != "second" || actions[1].Name != "third" {
+		t.Errorf("expected the latest two actions but got %v", actions)
+	}
+}
+
+func TestConfigOperator_handleAddSecret_recordsTheVariantInDryRun(t *testing.T) {
+	setup()
+	recorder := NewDryRunRecorder(20)
+	op = NewConfigOperator(NewDryRunUpsClientProvider(pushClientProvider, recorder), NewDryRunAnnotationHelper(recorder), NewDryRunKubeHelper(kubeHelper, recorder), NewDryRunEventHelper(recorder), NewDefaultPlatformRegistry(), constants.UPSPollingInterval*time.Second, NewHealth(time.Minute), constants.DriftMissedPolls, constants.DriftMaxDeletions, OrphanedVariantPolicyReport)
+
+	bindingSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "myBindingSecret",
+			Labels: map[string]string{"secretType": "mobile-client-binding-secret"},
+		},
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+
+	// Neither the PushVariant nor its credentials exist yet
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-android-credentials").Return(nil, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+
+	err := op.handleAddSecret(context.Background(), bindingSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	operations := map[string]bool{}
+	for _, action := range recorder.Actions() {
+		operations[action.Operation] = true
+	}
+	for _, expected := range []string{"createPushVariant", "createAndroidVariant", "createClientConfigSecret", "addAnnotationToMobileClient", "deleteSecret"} {
+		if !operations[expected] {
+			t.Errorf("expected %s to be recorded but got %v", expected, operations)
+		}
+	}
+	pushClient.AssertNotCalled(t, "createAndroidVariant", mock.Anything, mock.Anything)
+	kubeHelper.AssertNotCalled(t, "createPushVariant", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,110 @@
+package configOperator
+
+import (
+	"context"
+	"log"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	sc "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the events recorded by the operator
+const (
+	eventReasonVariantCreated        = "VariantCreated"
+	eventReasonVariantUpdated        = "VariantUpdated"
+	eventReasonVariantCreationFailed = "VariantCreationFailed"
+	eventReasonVariantDeleted        = "VariantDeleted"
+	eventReasonVariantDeletionFailed = "VariantDeletionFailed"
+	eventReasonVariantCleanupSkipped = "VariantCleanupSkipped"
+	eventReasonConfigSecretUpdated   = "ConfigSecretUpdated"
+	eventReasonDriftBindingDeleted   = "DriftBindingDeleted"
+
+	// Variants in UPS that no config secret refers to
+	eventReasonOrphanedVariantFound   = "OrphanedVariantFound"
+	eventReasonOrphanedVariantAdopted = "OrphanedVariantAdopted"
+	eventReasonOrphanedVariantDeleted = "OrphanedVariantDeleted"
+)
+
+// Records Kubernetes events so that developers can follow what happened to their bindings
+// with `oc describe`. Events for objects that cannot be found are only logged.
+type EventHelper interface {
+	// Records an event on the MobileClient with the given name
+	mobileClientEvent(ctx context.Context, clientId string, eventType string, reason string, message string)
+	// Records an event on the ServiceBinding with the given ExternalID
+	serviceBindingEvent(ctx context.Context, bindingId string, eventType string, reason string, message string)
+}
+
+type EventHelperImpl struct {
+	recorder     record.EventRecorder
+	mobileclient *mc.Clientset
+	scclient     *sc.Clientset
+	namespace    string
+}
+
+func NewEventHelper(recorder record.EventRecorder, mobileclient *mc.Clientset, scclient *sc.Clientset, namespace string) *EventHelperImpl {
+	helper := new(EventHelperImpl)
+
+	helper.recorder = recorder
+	helper.mobileclient = mobileclient
+	helper.scclient = scclient
+	helper.namespace = namespace
+
+	return helper
+}
+
+func (helper EventHelperImpl) mobileClientEvent(ctx context.Context, clientId string, eventType string, reason string, message string) {
+	client := &mobile.MobileClient{}
+	err := helper.mobileclient.MobileV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("mobileclients").
+		Name(clientId).
+		Do().
+		Into(client)
+	if err != nil {
+		log.Printf("Cannot record event %s on mobile client %s: %s", reason, clientId, contextError(ctx, err).Error())
+		return
+	}
+
+	ref := objectReference("MobileClient", mobile.SchemeGroupVersion.String(), client.ObjectMeta)
+	helper.recorder.Event(ref, eventType, reason, message)
+}
+
+func (helper EventHelperImpl) serviceBindingEvent(ctx context.Context, bindingId string, eventType string, reason string, message string) {
+	bindings := &v1beta1.ServiceBindingList{}
+	err := helper.scclient.ServicecatalogV1beta1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("servicebindings").
+		Do().
+		Into(bindings)
+	if err != nil {
+		log.Printf("Cannot record event %s on service binding %s: %s", reason, bindingId, contextError(ctx, err).Error())
+		return
+	}
+
+	for _, binding := range bindings.Items {
+		if binding.Spec.ExternalID == bindingId {
+			ref := objectReference("ServiceBinding", v1beta1.SchemeGroupVersion.String(), binding.ObjectMeta)
+			helper.recorder.Event(ref, eventType, reason, message)
+			return
+		}
+	}
+}
+
+// Objects returned by the typed clients have no kind, so the reference is built by hand
+func objectReference(kind string, apiVersion string, meta metav1.ObjectMeta) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		Kind:            kind,
+		APIVersion:      apiVersion,
+		Namespace:       meta.Namespace,
+		Name:            meta.Name,
+		UID:             meta.UID,
+		ResourceVersion: meta.ResourceVersion,
+	}
+}
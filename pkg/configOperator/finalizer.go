@@ -0,0 +1,156 @@
+package configOperator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Deletes the variants of a config secret that is being deleted from UPS and removes the
+// finalizer afterwards. The secret is kept and an error is returned while a variant cannot be
+// deleted, so that no variant is left behind in UPS.
+func (op ConfigOperator) finalizeConfigSecret(ctx context.Context, configSecret *v1.Secret) error {
+	if !hasFinalizer(configSecret.ObjectMeta, constants.ConfigSecretFinalizer) {
+		return nil
+	}
+
+	reason, err := op.skipVariantCleanup(ctx, configSecret)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		log.Printf("Removing the finalizer of config secret `%s` without deleting its variants from UPS: %s", configSecret.Name, reason)
+		op.eventHelper.mobileClientEvent(ctx, configSecret.Labels["clientId"], v1.EventTypeWarning, eventReasonVariantCleanupSkipped,
+			fmt.Sprintf("The variants of the config secret %s are left in UPS: %s", configSecret.Name, reason))
+	} else {
+		err = op.deleteVariantsOfConfigSecret(ctx, configSecret)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The informer cache must not be modified
+	updated := configSecret.DeepCopy()
+	removeFinalizer(&updated.ObjectMeta, constants.ConfigSecretFinalizer)
+	_, err = op.kubeHelper.updateSecret(ctx, updated)
+	if err != nil {
+		return errors.Wrapf(err, "cannot remove the finalizer of config secret `%s`", configSecret.Name)
+	}
+
+	log.Printf("Removed the finalizer of config secret `%s`", configSecret.Name)
+	return nil
+}
+
+// Returns why the variants of a config secret cannot be deleted from UPS, or an empty string if
+// they should be. Without a way out, the secret and with it its namespace would be kept forever
+// once UPS cannot be reached anymore.
+func (op ConfigOperator) skipVariantCleanup(ctx context.Context, configSecret *v1.Secret) (string, error) {
+	if configSecret.Annotations[constants.SkipCleanupAnnotation] == "true" {
+		return fmt.Sprintf("the secret has the %s annotation", constants.SkipCleanupAnnotation), nil
+	}
+
+	// The variants are deleted with a client that has already been built, even if the UPS secret
+	// is gone, e.g. because the namespace is being deleted
+	if op.pushClientProvider.getPushClient(ctx) != nil {
+		return "", nil
+	}
+	exists, err := op.pushClientProvider.upsSecretExists(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot look up the UPS secret")
+	}
+	if !exists {
+		return "the UPS secret does not exist", nil
+	}
+	return "", nil
+}
+
+// Deletes all variants in the config of a config secret from UPS. Failures are reported as an
+// event on the mobile client.
+func (op ConfigOperator) deleteVariantsOfConfigSecret(ctx context.Context, configSecret *v1.Secret) error {
+	clientId := configSecret.Labels["clientId"]
+
+	var config map[string]json.RawMessage
+	json.Unmarshal(configSecret.Data["config"], &config)
+
+	appTypes := make([]string, 0, len(config))
+	for appType := range config {
+		appTypes = append(appTypes, appType)
+	}
+	sort.Strings(appTypes)
+
+	var failed []string
+	var lastErr error
+	for _, appType := range appTypes {
+		platform := op.platforms.get(appType)
+		variantId := op.getVariantIdFromConfig(string(config[appType]))
+		if platform == nil || variantId == "" {
+			continue
+		}
+
+		err := op.deleteVariantOfConfigSecret(ctx, platform, variantId)
+		if err != nil {
+			log.Printf("Cannot delete the %s variant %s of config secret `%s`: %s", appType, variantId, configSecret.Name, err.Error())
+			failed = append(failed, variantId)
+			lastErr = err
+			continue
+		}
+		op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeNormal, eventReasonVariantDeleted,
+			fmt.Sprintf("The %s variant %s has been deleted from UPS", appType, variantId))
+	}
+
+	if lastErr != nil && ctx.Err() == nil {
+		op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeWarning, eventReasonVariantDeletionFailed,
+			fmt.Sprintf("Cannot delete the variants %s from UPS, keeping the config secret %s until they are deleted: %s", strings.Join(failed, ", "), configSecret.Name, lastErr.Error()))
+	}
+	return lastErr
+}
+
+// Variants that are already gone, e.g. because the deleted binding was handled, count as deleted
+func (op ConfigOperator) deleteVariantOfConfigSecret(ctx context.Context, platform Platform, variantId string) error {
+	pushClient := op.pushClientProvider.getPushClient(ctx)
+	if pushClient == nil {
+		return errNoPushClient
+	}
+
+	// The binding is unknown, platforms with several UPS resources try all of them
+	err := platform.deleteVariant(ctx, pushClient, &BindingSecret{}, variantId)
+	if IsUpsNotFound(err) {
+		log.Printf("Variant %s does not exist in UPS, nothing to delete", variantId)
+		return nil
+	}
+	return err
+}
+
+func hasFinalizer(meta metav1.ObjectMeta, finalizer string) bool {
+	for _, existing := range meta.Finalizers {
+		if existing == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func addFinalizer(meta *metav1.ObjectMeta, finalizer string) {
+	if !hasFinalizer(*meta, finalizer) {
+		meta.Finalizers = append(meta.Finalizers, finalizer)
+	}
+}
+
+func removeFinalizer(meta *metav1.ObjectMeta, finalizer string) {
+	var finalizers []string
+	for _, existing := range meta.Finalizers {
+		if existing != finalizer {
+			finalizers = append(finalizers, existing)
+		}
+	}
+	meta.Finalizers = finalizers
+}
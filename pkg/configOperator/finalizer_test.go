@@ -0,0 +1,154 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+func newDeletingConfigSecret() *v1.Secret {
+	deletionTimestamp := metav1.Now()
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "mySecretName",
+			Namespace:         "myNamespace",
+			Labels:            map[string]string{"clientId": "myClientId", "serviceName": "ups"},
+			Finalizers:        []string{"push.aerogear.org/delete-variants"},
+			DeletionTimestamp: &deletionTimestamp,
+		},
+		Data: map[string][]byte{
+			"config": []byte("{\"android\":{\"variantId\":\"myAndroidVariantId\"},\"ios\":{\"variantId\":\"myIOSVariantId\"}}"),
+		},
+	}
+}
+
+func TestConfigOperator_finalizeConfigSecret_deletesTheVariants(t *testing.T) {
+	setup()
+
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(nil).Once()
+	// the iOS variant has already been deleted
+	pushClient.On("deleteVariant", mock.Anything, "ios", "myIOSVariantId").Return(&UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	pushClient.On("deleteVariant", mock.Anything, "ios_token", "myIOSVariantId").Return(&UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	configSecret := newDeletingConfigSecret()
+	err := op.finalizeConfigSecret(context.Background(), configSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertExpectations(t)
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return len(secret.Finalizers) == 0
+	}))
+	if len(configSecret.Finalizers) != 1 {
+		t.Errorf("expected the informer cache not to be modified")
+	}
+}
+
+func TestConfigOperator_finalizeConfigSecret_keepsTheSecretWhileUPSFails(t *testing.T) {
+	setup()
+
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(errUpsCircuitOpen)
+	pushClient.On("deleteVariant", mock.Anything, "ios", "myIOSVariantId").Return(nil)
+
+	err := op.finalizeConfigSecret(context.Background(), newDeletingConfigSecret())
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected the secret to be finalized again later but got %v", err)
+	}
+
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeWarning, "VariantDeletionFailed", mock.Anything)
+}
+
+func TestConfigOperator_finalizeConfigSecret_removesTheFinalizerOnceTheUPSSecretIsGone(t *testing.T) {
+	setup()
+
+	pushClientProvider = new(MockUpsClientProvider)
+	pushClientProvider.On("getPushClient", mock.Anything).Return(nil)
+	pushClientProvider.On("upsSecretExists", mock.Anything).Return(false, nil)
+	op.pushClientProvider = pushClientProvider
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.finalizeConfigSecret(context.Background(), newDeletingConfigSecret())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return len(secret.Finalizers) == 0
+	}))
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeWarning, "VariantCleanupSkipped", mock.Anything)
+}
+
+func TestConfigOperator_finalizeConfigSecret_keepsTheSecretWhileThePushClientCannotBeBuilt(t *testing.T) {
+	setup()
+
+	pushClientProvider = new(MockUpsClientProvider)
+	pushClientProvider.On("getPushClient", mock.Anything).Return(nil)
+	// e.g. no PushApplication has been created yet
+	pushClientProvider.On("upsSecretExists", mock.Anything).Return(true, nil)
+	op.pushClientProvider = pushClientProvider
+
+	err := op.finalizeConfigSecret(context.Background(), newDeletingConfigSecret())
+	if err != errNoPushClient {
+		t.Errorf("expected the secret to be finalized again later but got %v", err)
+	}
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_finalizeConfigSecret_skipsTheCleanupIfAnnotated(t *testing.T) {
+	setup()
+
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	configSecret := newDeletingConfigSecret()
+	configSecret.Annotations = map[string]string{"push.aerogear.org/skip-cleanup": "true"}
+	err := op.finalizeConfigSecret(context.Background(), configSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return len(secret.Finalizers) == 0
+	}))
+}
+
+func TestSecretController_finalizesDeletedConfigSecrets(t *testing.T) {
+	setup()
+
+	configSecret := newDeletingConfigSecret()
+	configSecret.Data["config"] = []byte("{\"android\":{\"variantId\":\"myAndroidVariantId\"}}")
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{Items: []v1.Secret{*configSecret}}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(nil)
+
+	calls := make(chan string, 10)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil).Run(func(args mock.Arguments) {
+		calls <- "updateSecret"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSecretController(*op).run(ctx, ctx.Done())
+
+	waitFor(t, calls, "updateSecret")
+	cancel()
+
+	pushClient.AssertCalled(t, "deleteVariant", mock.Anything, "android", "myAndroidVariantId")
+}
@@ -0,0 +1,143 @@
+package configOperator
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tracks whether the secret watch and the UPS poll make progress, for the liveness and
+// readiness endpoints. A replica that waits to become the leader is live and ready.
+type Health struct {
+	mutex          sync.Mutex
+	livenessWindow time.Duration
+	running        bool
+	// The informer caches are synced and the worker handles the queue
+	watching          bool
+	lastWatchProgress time.Time
+	polling           bool
+	lastPollProgress  time.Time
+}
+
+// The loops have to make progress within livenessWindow. It has to be longer than the UPS
+// polling interval.
+func NewHealth(livenessWindow time.Duration) *Health {
+	health := new(Health)
+
+	health.livenessWindow = livenessWindow
+
+	return health
+}
+
+func (health *Health) setRunning(running bool) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.running = running
+}
+
+func (health *Health) setWatching(watching bool) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.watching = watching
+	health.lastWatchProgress = time.Now()
+}
+
+func (health *Health) watchProgressed() {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.lastWatchProgress = time.Now()
+}
+
+func (health *Health) setPolling(polling bool) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.polling = polling
+	health.lastPollProgress = time.Now()
+}
+
+func (health *Health) pollProgressed() {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.lastPollProgress = time.Now()
+}
+
+// The worker is asked to confirm that it is alive this often
+func (health *Health) heartbeatInterval() time.Duration {
+	return health.livenessWindow / 4
+}
+
+// Returns an error if the watch or the poll loop made no progress within the liveness window
+func (health *Health) checkLiveness() error {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	var problems []string
+	now := time.Now()
+	if health.watching && now.Sub(health.lastWatchProgress) > health.livenessWindow {
+		problems = append(problems, "the secret watch made no progress since "+health.lastWatchProgress.Format(time.RFC3339))
+	}
+	if health.polling && now.Sub(health.lastPollProgress) > health.livenessWindow {
+		problems = append(problems, "the UPS poll made no progress since "+health.lastPollProgress.Format(time.RFC3339))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Returns an error if the operator cannot handle bindings yet
+func (op ConfigOperator) checkReadiness() error {
+	op.health.mutex.Lock()
+	running, watching := op.health.running, op.health.watching
+	op.health.mutex.Unlock()
+
+	if !running {
+		return nil
+	}
+
+	var problems []string
+	if !watching {
+		problems = append(problems, "the secret watch is not established")
+	}
+
+	status := op.pushClientProvider.getStatus()
+	if !status.UpsSecretFound {
+		problems = append(problems, "the UPS secret was not found")
+	} else if !status.ClientBuilt {
+		problems = append(problems, "the push client could not be built from the UPS secret")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Handler of the liveness endpoint, responds with 503 if a loop is stuck
+func (op ConfigOperator) ServeHealthz(w http.ResponseWriter, r *http.Request) {
+	writeCheckResult(w, op.health.checkLiveness())
+}
+
+// Handler of the readiness endpoint, responds with 503 if the operator cannot handle bindings
+func (op ConfigOperator) ServeReadyz(w http.ResponseWriter, r *http.Request) {
+	writeCheckResult(w, op.checkReadiness())
+}
+
+func writeCheckResult(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
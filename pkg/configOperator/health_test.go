@@ -0,0 +1,70 @@
+package configOperator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth_failsLivenessWhenALoopMakesNoProgress(t *testing.T) {
+	health := NewHealth(time.Minute)
+	health.setRunning(true)
+	health.setWatching(true)
+	health.setPolling(true)
+
+	if err := health.checkLiveness(); err != nil {
+		t.Fatalf("unexpected liveness error: %v", err)
+	}
+
+	health.lastPollProgress = time.Now().Add(-2 * time.Minute)
+
+	err := health.checkLiveness()
+	if err == nil || !strings.Contains(err.Error(), "UPS poll") {
+		t.Errorf("expected the UPS poll to fail the liveness check but got %v", err)
+	}
+
+	// a stopped loop cannot make progress
+	health.setPolling(false)
+	health.lastPollProgress = time.Now().Add(-2 * time.Minute)
+
+	if err := health.checkLiveness(); err != nil {
+		t.Errorf("unexpected liveness error: %v", err)
+	}
+}
+
+func TestConfigOperator_ServeReadyz(t *testing.T) {
+	setup()
+
+	serveReadyz := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		op.ServeReadyz(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return recorder
+	}
+
+	// standby replicas are ready
+	if recorder := serveReadyz(); recorder.Code != http.StatusOK {
+		t.Errorf("expected a standby replica to be ready but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	op.health.setRunning(true)
+	pushClientProvider.On("getStatus").Return(UpsClientStatus{}).Once()
+
+	recorder := serveReadyz()
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 but got %d", recorder.Code)
+	}
+	for _, problem := range []string{"secret watch is not established", "UPS secret was not found"} {
+		if !strings.Contains(recorder.Body.String(), problem) {
+			t.Errorf("expected `%s` in the response but got %s", problem, recorder.Body.String())
+		}
+	}
+
+	op.health.setWatching(true)
+	pushClientProvider.On("getStatus").Return(UpsClientStatus{UpsSecretFound: true, ClientBuilt: true})
+
+	if recorder := serveReadyz(); recorder.Code != http.StatusOK {
+		t.Errorf("expected the operator to be ready but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
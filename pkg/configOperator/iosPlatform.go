@@ -0,0 +1,121 @@
+package configOperator
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"github.com/pkg/errors"
+)
+
+// iOS variants use APNs, either with a certificate or with a .p8 key. UPS keeps
+// the two kinds as separate resources.
+type IOSPlatform struct{}
+
+func (platform *IOSPlatform) getName() string {
+	return "ios"
+}
+
+func (platform *IOSPlatform) getLabel() string {
+	return "iOS"
+}
+
+// Bindings that contain a private key use token based authentication instead of a certificate
+func (platform *IOSPlatform) parseBinding(secret *BindingSecret) (PlatformVariant, error) {
+	clientId := string(secret.Data[constants.BindingDataClientIdKey])
+	isProductionString := string(secret.Data[constants.BindingDataIOSIsProductionKey])
+	isProduction, err := strconv.ParseBool(isProductionString)
+
+	if err != nil {
+		log.Printf("iOS variant with clientId %v is invalid, isProduction value %v should be true or false. Setting to false", clientId, isProductionString)
+		isProduction = false
+	}
+
+	if isIOSTokenBinding(secret) {
+		return &IOSTokenVariant{
+			PrivateKey: string(secret.Data[constants.BindingDataIOSPrivateKeyKey]),
+			KeyID:      string(secret.Data[constants.BindingDataIOSKeyIdKey]),
+			TeamID:     string(secret.Data[constants.BindingDataIOSTeamIdKey]),
+			BundleID:   string(secret.Data[constants.BindingDataIOSBundleIdKey]),
+			Production: isProduction,
+		}, nil
+	}
+
+	return &IOSVariant{
+		Certificate: secret.Data[constants.BindingDataIOSCertKey],
+		Passphrase:  string(secret.Data[constants.BindingDataIOSPassPhraseKey]),
+		Production:  isProduction,
+	}, nil
+}
+
+func (platform *IOSPlatform) createVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	var created PlatformVariant
+	var err error
+
+	switch v := variant.(type) {
+	case *IOSTokenVariant:
+		created, err = pushClient.createIOSTokenVariant(ctx, v)
+	case *IOSVariant:
+		created, err = pushClient.createIOSVariant(ctx, v)
+	default:
+		return nil, errors.Errorf("unsupported iOS variant %T", variant)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (platform *IOSPlatform) findVariant(ctx context.Context, pushClient UpsClient, name string) (*Variant, error) {
+	return findVariantByName(ctx, pushClient, name, "ios", "ios_token")
+}
+
+func (platform *IOSPlatform) listVariants(ctx context.Context, pushClient UpsClient) ([]Variant, error) {
+	return listVariantsOf(ctx, pushClient, "ios", "ios_token")
+}
+
+func (platform *IOSPlatform) getVariant(ctx context.Context, pushClient UpsClient, variantId string) (*Variant, error) {
+	return getVariantOf(ctx, pushClient, variantId, "ios", "ios_token")
+}
+
+// Switching between certificate and token results in a not found error, the caller then creates a new
+// variant and deletes the old one
+func (platform *IOSPlatform) updateVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	var updated PlatformVariant
+	var err error
+
+	switch v := variant.(type) {
+	case *IOSTokenVariant:
+		updated, err = pushClient.updateIOSTokenVariant(ctx, v)
+	case *IOSVariant:
+		updated, err = pushClient.updateIOSVariant(ctx, v)
+	default:
+		return nil, errors.Errorf("unsupported iOS variant %T", variant)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// The binding might not contain the credentials anymore, e.g. when the deletion was missed. The
+// other resource is tried if the variant is not found.
+func (platform *IOSPlatform) deleteVariant(ctx context.Context, pushClient UpsClient, secret *BindingSecret, variantId string) error {
+	resource, otherResource := "ios", "ios_token"
+	if isIOSTokenBinding(secret) {
+		resource, otherResource = otherResource, resource
+	}
+
+	err := pushClient.deleteVariant(ctx, resource, variantId)
+	if IsUpsNotFound(err) {
+		return pushClient.deleteVariant(ctx, otherResource, variantId)
+	}
+	return err
+}
+
+func isIOSTokenBinding(secret *BindingSecret) bool {
+	return len(secret.Data[constants.BindingDataIOSPrivateKeyKey]) > 0
+}
@@ -1,107 +1,286 @@
 package configOperator
 
 import (
+	"context"
+
 	"github.com/aerogear/ups-config-operator/pkg/constants"
 	"fmt"
-	"os"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"log"
 	"github.com/pkg/errors"
 
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
 	sc "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
 	"math/rand"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"time"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	mc "github.com/aerogear/mobile-crd-client/pkg/client/mobile/clientset/versioned"
+	"github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/client/push/clientset/versioned"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// All requests are cancelled once ctx is done, the calls return ctx.Err() then
 type KubeHelper interface {
-	startSecretWatch() (watch.Interface, error)
-	listSecrets(selector string) (*v1.SecretList, error)
-	deleteSecret(name string)
-	getServiceBindingNameByID(bindingId string) (string, error)
-	findMobileClientConfig(clientId string) *v1.Secret
-	createClientConfigSecret(clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) *v1.Secret
-	updateSecret(secret *v1.Secret) (*v1.Secret, error)
-	deleteServiceBinding(bindingName string) error
+	// Informers are started by the caller
+	newSecretInformer(selector string, resync time.Duration) cache.SharedIndexInformer
+	newServiceBindingInformer(resync time.Duration) cache.SharedIndexInformer
+	newPushVariantInformer(resync time.Duration) cache.SharedIndexInformer
+	newPushApplicationInformer(resync time.Duration) cache.SharedIndexInformer
+	newMobileClientInformer(resync time.Duration) cache.SharedIndexInformer
+	listSecrets(ctx context.Context, selector string) (*v1.SecretList, error)
+	deleteSecret(ctx context.Context, name string)
+	getServiceBindingByID(ctx context.Context, bindingId string) (*v1beta1.ServiceBinding, error)
+	listServiceBindings(ctx context.Context) (*v1beta1.ServiceBindingList, error)
+	findMobileClientConfig(ctx context.Context, clientId string) (*v1.Secret, error)
+	createClientConfigSecret(ctx context.Context, clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) (*v1.Secret, error)
+	updateSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error)
+	deleteServiceBinding(ctx context.Context, bindingName string) error
+	// Returns nil if the secret does not exist
+	getSecret(ctx context.Context, name string) (*v1.Secret, error)
+	createSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error)
+	// Returns nil if the PushVariant does not exist
+	getPushVariant(ctx context.Context, name string) (*v1alpha1.PushVariant, error)
+	createPushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error)
+	listPushVariants(ctx context.Context, selector string) (*v1alpha1.PushVariantList, error)
+	// Updates the spec and the status, PushVariants have no status subresource
+	updatePushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error)
+	deletePushVariant(ctx context.Context, name string) error
+	// Updates the spec and the status, PushApplications have no status subresource
+	updatePushApplication(ctx context.Context, pushApplication *v1alpha1.PushApplication) (*v1alpha1.PushApplication, error)
+	// Returns nil if the MobileClient does not exist
+	getMobileClient(ctx context.Context, name string) (*mobile.MobileClient, error)
 }
 
 type KubeHelperImpl struct {
-	k8client *kubernetes.Clientset
-	scclient *sc.Clientset
+	k8client     *kubernetes.Clientset
+	scclient     *sc.Clientset
+	pushclient   *push.Clientset
+	mobileclient *mc.Clientset
+	// Used for watches, must not have a request timeout
+	watchclient       *kubernetes.Clientset
+	scwatchclient     *sc.Clientset
+	pushwatchclient   *push.Clientset
+	mobilewatchclient *mc.Clientset
+	namespace         string
 }
 
-func NewKubeHelper(k8client *kubernetes.Clientset, scclient *sc.Clientset) *KubeHelperImpl {
+func NewKubeHelper(k8client *kubernetes.Clientset, watchclient *kubernetes.Clientset, scclient *sc.Clientset, scwatchclient *sc.Clientset, pushclient *push.Clientset, pushwatchclient *push.Clientset, mobileclient *mc.Clientset, mobilewatchclient *mc.Clientset, namespace string) *KubeHelperImpl {
 	helper := new(KubeHelperImpl)
 
 	helper.k8client = k8client
+	helper.watchclient = watchclient
 	helper.scclient = scclient
+	helper.scwatchclient = scwatchclient
+	helper.pushclient = pushclient
+	helper.pushwatchclient = pushwatchclient
+	helper.mobileclient = mobileclient
+	helper.mobilewatchclient = mobilewatchclient
+	helper.namespace = namespace
 
 	return helper
 }
 
-func (helper KubeHelperImpl) startSecretWatch() (watch.Interface, error) {
-	return helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).Watch(metav1.ListOptions{})
+// Returns an informer for the secrets that match the label selector
+func (helper KubeHelperImpl) newSecretInformer(selector string, resync time.Duration) cache.SharedIndexInformer {
+	namespace := helper.namespace
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector
+			return helper.k8client.CoreV1().Secrets(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector
+			return helper.watchclient.CoreV1().Secrets(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &v1.Secret{}, resync, cache.Indexers{})
 }
 
-func (helper KubeHelperImpl) listSecrets(selector string) (*v1.SecretList, error) {
-	filter := metav1.ListOptions{LabelSelector: selector}
-	return helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).List(filter)
+// Returns an informer for the service bindings
+func (helper KubeHelperImpl) newServiceBindingInformer(resync time.Duration) cache.SharedIndexInformer {
+	namespace := helper.namespace
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return helper.scclient.ServicecatalogV1beta1().ServiceBindings(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return helper.scwatchclient.ServicecatalogV1beta1().ServiceBindings(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &v1beta1.ServiceBinding{}, resync, cache.Indexers{})
+}
+
+// Returns an informer for the PushVariants
+func (helper KubeHelperImpl) newPushVariantInformer(resync time.Duration) cache.SharedIndexInformer {
+	namespace := helper.namespace
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return helper.pushclient.PushV1alpha1().PushVariants(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return helper.pushwatchclient.PushV1alpha1().PushVariants(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &v1alpha1.PushVariant{}, resync, cache.Indexers{})
+}
+
+// Returns an informer for the PushApplications
+func (helper KubeHelperImpl) newPushApplicationInformer(resync time.Duration) cache.SharedIndexInformer {
+	namespace := helper.namespace
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return helper.pushclient.PushV1alpha1().PushApplications(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return helper.pushwatchclient.PushV1alpha1().PushApplications(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &v1alpha1.PushApplication{}, resync, cache.Indexers{})
 }
 
-// Find a mobile client bound ups config secret
-func (helper KubeHelperImpl) findMobileClientConfig(clientId string) *v1.Secret {
-	filter := metav1.ListOptions{LabelSelector: fmt.Sprintf("clientId=%s,serviceName=ups", clientId)}
-	secrets, err := helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).List(filter)
+// Returns an informer for the MobileClients
+func (helper KubeHelperImpl) newMobileClientInformer(resync time.Duration) cache.SharedIndexInformer {
+	namespace := helper.namespace
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return helper.mobileclient.MobileV1alpha1().MobileClients(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return helper.mobilewatchclient.MobileV1alpha1().MobileClients(namespace).Watch(options)
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, &mobile.MobileClient{}, resync, cache.Indexers{})
+}
 
-	// TODO: remove error handling here!
+func (helper KubeHelperImpl) listSecrets(ctx context.Context, selector string) (*v1.SecretList, error) {
+	filter := metav1.ListOptions{LabelSelector: selector}
+
+	result := &v1.SecretList{}
+	err := helper.k8client.CoreV1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("secrets").
+		VersionedParams(&filter, metav1.ParameterCodec).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+// Find a mobile client bound ups config secret. Returns nil if there is none yet.
+func (helper KubeHelperImpl) findMobileClientConfig(ctx context.Context, clientId string) (*v1.Secret, error) {
+	secrets, err := helper.listSecrets(ctx, fmt.Sprintf("clientId=%s,serviceName=ups", clientId))
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+
+	// Secrets that are being deleted only wait for their variants to be deleted from UPS
+	var configSecrets []v1.Secret
+	for _, secret := range secrets.Items {
+		if secret.DeletionTimestamp == nil {
+			configSecrets = append(configSecrets, secret)
+		}
 	}
 
 	// No secret exists yet, that's ok, we have to create one
-	if len(secrets.Items) == 0 {
-		return nil
+	if len(configSecrets) == 0 {
+		return nil, nil
 	}
 
-	// TODO: remove error handling here!
 	// Multiple secrets for the same clientId found, that's an error
-	if len(secrets.Items) > 1 {
-		panic(fmt.Sprintf("Multiple secrets found for clientId %s", clientId))
+	if len(configSecrets) > 1 {
+		return nil, errors.New(fmt.Sprintf("Multiple secrets found for clientId %s", clientId))
 	}
 
-	return &secrets.Items[0]
+	return &configSecrets[0], nil
 }
 
 // Find a service binding by its ExternalID
-func (helper KubeHelperImpl) getServiceBindingNameByID(bindingId string) (string, error) {
+func (helper KubeHelperImpl) getServiceBindingByID(ctx context.Context, bindingId string) (*v1beta1.ServiceBinding, error) {
 	// Get a list of all service bindings in the namespace and find the one with a matching ExternalID
 	// This is not very efficient and could be improved with a jsonpath query but it looks like client-go
 	// does not support jsonpath or at least I could not find any examples.
-	bindings, err := helper.scclient.ServicecatalogV1beta1().ServiceBindings(os.Getenv(constants.EnvVarKeyNamespace)).List(metav1.ListOptions{})
+	bindings, err := helper.listServiceBindings(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	for _, binding := range bindings.Items {
+	for i, binding := range bindings.Items {
 		log.Printf("Checking service binding %s", binding.Name)
 		if binding.Spec.ExternalID == bindingId {
-			return binding.Name, nil
+			return &bindings.Items[i], nil
 		}
 	}
 
-	return "", errors.New(fmt.Sprintf("Can't find a binding with ExternalID %s", bindingId))
+	return nil, errors.New(fmt.Sprintf("Can't find a binding with ExternalID %s", bindingId))
 }
 
-func (helper KubeHelperImpl) deleteServiceBinding(bindingName string) error {
-	return helper.scclient.ServicecatalogV1beta1().ServiceBindings(os.Getenv(constants.EnvVarKeyNamespace)).Delete(bindingName, nil)
+// Lists all service bindings in the namespace
+func (helper KubeHelperImpl) listServiceBindings(ctx context.Context) (*v1beta1.ServiceBindingList, error) {
+	bindings := &v1beta1.ServiceBindingList{}
+	err := helper.scclient.ServicecatalogV1beta1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("servicebindings").
+		Do().
+		Into(bindings)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return bindings, nil
+}
+
+func (helper KubeHelperImpl) deleteServiceBinding(ctx context.Context, bindingName string) error {
+	err := helper.scclient.ServicecatalogV1beta1().RESTClient().Delete().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("servicebindings").
+		Name(bindingName).
+		Do().
+		Error()
+	return contextError(ctx, err)
 }
 
 // Creates a mobile client bound ups config secret
-func (helper KubeHelperImpl) createClientConfigSecret(clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) *v1.Secret {
+func (helper KubeHelperImpl) createClientConfigSecret(ctx context.Context, clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) (*v1.Secret, error) {
+	// The secret is garbage collected together with the mobile client
+	mobileClient, err := helper.getMobileClient(ctx, clientId)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot look up the mobile client")
+	}
+	payload := newClientConfigSecret(clientId, serviceInstanceName, serviceInstanceId, pushAppId, mobileClient)
+	configSecretName := payload.Name
+
+	secret, err := helper.createSecret(ctx, payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating ups config secret")
+	}
+
+	log.Printf("Config secret `%s` for variant created", configSecretName)
+	return secret, nil
+}
+
+// Returns a new config secret with an empty config, owned by the mobile client if it exists
+func newClientConfigSecret(clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string, mobileClient *mobile.MobileClient) *v1.Secret {
 	configSecretName := fmt.Sprintf("ups-secret-%s-%s", clientId, getRandomIdentifier(5))
 
-	payload := v1.Secret{
+	var ownerReferences []metav1.OwnerReference
+	if mobileClient != nil {
+		ownerReferences = []metav1.OwnerReference{mobileClientOwnerReference(mobileClient)}
+	} else {
+		log.Printf("Mobile client `%s` does not exist, creating config secret `%s` without an owner", clientId, configSecretName)
+	}
+
+	return &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: configSecretName,
 			Labels: map[string]string{
@@ -113,6 +292,9 @@ func (helper KubeHelperImpl) createClientConfigSecret(clientId string, serviceIn
 				"clientId":          clientId,
 				"pushApplicationId": pushAppId,
 			},
+			// The variants are deleted from UPS before the secret is removed
+			Finalizers:      []string{constants.ConfigSecretFinalizer},
+			OwnerReferences: ownerReferences,
 		},
 		Data: map[string][]byte{
 			// Used to generate the name of the UI annotations
@@ -120,35 +302,206 @@ func (helper KubeHelperImpl) createClientConfigSecret(clientId string, serviceIn
 			"config":                                    []byte("{}"),
 		},
 	}
+}
 
-	secret, err := helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).Create(&payload)
+func (helper KubeHelperImpl) updateSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	result := &v1.Secret{}
+	err := helper.k8client.CoreV1().RESTClient().Put().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("secrets").
+		Name(secret.Name).
+		Body(secret).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
 
-	// TODO: remove error handling here!
+func (helper KubeHelperImpl) getSecret(ctx context.Context, name string) (*v1.Secret, error) {
+	result := &v1.Secret{}
+	err := helper.k8client.CoreV1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("secrets").
+		Name(name).
+		Do().
+		Into(result)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
-		log.Fatal("Error creating ups config secret", err)
-	} else {
-		log.Printf("Config secret `%s` for variant created", configSecretName)
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) createSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	result := &v1.Secret{}
+	err := helper.k8client.CoreV1().RESTClient().Post().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("secrets").
+		Body(secret).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) getPushVariant(ctx context.Context, name string) (*v1alpha1.PushVariant, error) {
+	result := &v1alpha1.PushVariant{}
+	err := helper.pushclient.PushV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushvariants").
+		Name(name).
+		Do().
+		Into(result)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) createPushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error) {
+	result := &v1alpha1.PushVariant{}
+	err := helper.pushclient.PushV1alpha1().RESTClient().Post().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushvariants").
+		Body(pushVariant).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) updatePushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error) {
+	result := &v1alpha1.PushVariant{}
+	err := helper.pushclient.PushV1alpha1().RESTClient().Put().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushvariants").
+		Name(pushVariant.Name).
+		Body(pushVariant).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) listPushVariants(ctx context.Context, selector string) (*v1alpha1.PushVariantList, error) {
+	filter := metav1.ListOptions{LabelSelector: selector}
+
+	result := &v1alpha1.PushVariantList{}
+	err := helper.pushclient.PushV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushvariants").
+		VersionedParams(&filter, metav1.ParameterCodec).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
 	}
+	return result, nil
+}
 
-	return secret
+func (helper KubeHelperImpl) deletePushVariant(ctx context.Context, name string) error {
+	err := helper.pushclient.PushV1alpha1().RESTClient().Delete().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushvariants").
+		Name(name).
+		Do().
+		Error()
+	return contextError(ctx, err)
 }
 
-func (helper KubeHelperImpl) updateSecret(secret *v1.Secret) (*v1.Secret, error) {
-	return helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).Update(secret)
+func (helper KubeHelperImpl) updatePushApplication(ctx context.Context, pushApplication *v1alpha1.PushApplication) (*v1alpha1.PushApplication, error) {
+	result := &v1alpha1.PushApplication{}
+	err := helper.pushclient.PushV1alpha1().RESTClient().Put().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("pushapplications").
+		Name(pushApplication.Name).
+		Body(pushApplication).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+func (helper KubeHelperImpl) getMobileClient(ctx context.Context, name string) (*mobile.MobileClient, error) {
+	result := &mobile.MobileClient{}
+	err := helper.mobileclient.MobileV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("mobileclients").
+		Name(name).
+		Do().
+		Into(result)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return result, nil
+}
+
+// Makes an object owned by a mobile client, so that it is deleted together with the client
+func mobileClientOwnerReference(mobileClient *mobile.MobileClient) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: mobile.SchemeGroupVersion.String(),
+		Kind:       "MobileClient",
+		Name:       mobileClient.Name,
+		UID:        mobileClient.UID,
+	}
 }
 
 // Deletes a secret
-func (helper KubeHelperImpl) deleteSecret(name string) {
-	err := helper.k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).Delete(name, nil)
+func (helper KubeHelperImpl) deleteSecret(ctx context.Context, name string) {
+	err := helper.k8client.CoreV1().RESTClient().Delete().
+		Context(ctx).
+		Namespace(helper.namespace).
+		Resource("secrets").
+		Name(name).
+		Do().
+		Error()
 
 	// TODO: remove error handling here!
 	if err != nil {
-		log.Print("Error deleting secret", err)
+		log.Print("Error deleting secret", contextError(ctx, err))
 	} else {
 		log.Printf("Secret `%s` has been deleted", name)
 	}
 }
 
+// Requests are sent with ctx, so that they are cancelled when it is done. The typed clients of
+// client-go don't accept a context, so the requests are built with their REST clients instead.
+// Returns ctx.Err() in place of the error of a cancelled request.
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
 // Create a random identifier of the given length. Useful for randomized resource names
 func getRandomIdentifier(length int) string {
 	result := make([]rune, length)
@@ -0,0 +1,95 @@
+package configOperator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "ups_config_operator"
+
+// Outcomes of handling a binding secret or syncing a PushVariant
+const (
+	bindingOutcomeSuccess     = "success"
+	bindingOutcomeFailed      = "failed"
+	bindingOutcomeRetry       = "retry"
+	bindingOutcomeUnsupported = "unsupported"
+)
+
+// Reasons why the service binding of a variant missing from UPS has been kept
+const (
+	driftSkipUnconfirmed = "unconfirmed"
+	driftSkipProtected   = "protected"
+	driftSkipLimit       = "limit"
+)
+
+// Status of UPS requests that got no response
+const (
+	upsRequestStatusError     = "error"
+	upsRequestStatusCancelled = "cancelled"
+)
+
+var (
+	bindingSecretsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "binding_secrets_processed_total",
+		Help:      "Binding secrets stored as PushVariants by platform and outcome. Secrets with the `retry` outcome are handled again later.",
+	}, []string{"platform", "outcome"})
+
+	pushVariantSyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "push_variant_syncs_total",
+		Help:      "Attempts to bring the variants in UPS in line with the PushVariants by platform and outcome. PushVariants with the `retry` outcome are synced again later.",
+	}, []string{"platform", "outcome"})
+
+	upsRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "ups_request_duration_seconds",
+		Help:      "Latency of single requests to UPS by method, endpoint and status code. Retries are separate requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint", "status"})
+
+	driftDeletedVariants = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_deleted_variants_total",
+		Help:      "Variants that have been deleted in UPS and whose service bindings have been removed by the operator.",
+	})
+
+	driftSkippedDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_skipped_deletions_total",
+		Help:      "Service bindings of variants missing from UPS that have been kept by reason. Unconfirmed and limited deletions are retried with the next poll.",
+	}, []string{"reason"})
+
+	orphanedVariantsFound = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_variants",
+		Help:      "Variants in UPS without a config secret seen by the last poll of UPS.",
+	})
+
+	orphanedVariantsHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_variants_handled_total",
+		Help:      "Orphaned variants that have been adopted or deleted by action.",
+	}, []string{"action"})
+
+	dryRunActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dry_run_actions_total",
+		Help:      "Changes to UPS and Kubernetes that have been recorded instead of made in dry-run mode by target and operation.",
+	}, []string{"target", "operation"})
+
+	configSecrets = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "config_secrets",
+		Help:      "Config secrets of the push application seen by the last poll of UPS.",
+	})
+
+	lastSuccessfulPoll = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_successful_poll_timestamp_seconds",
+		Help:      "Unix time of the last poll that compared all config secrets with the variants in UPS.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(bindingSecretsProcessed, pushVariantSyncs, upsRequestDuration, driftDeletedVariants, driftSkippedDeletions, orphanedVariantsFound, orphanedVariantsHandled, dryRunActions, configSecrets, lastSuccessfulPoll)
+}
@@ -0,0 +1,73 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	metric := &dto.Metric{}
+	if err := counter.Write(metric); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestHandleAddSecret_countsBindingSecretsByPlatformAndOutcome(t *testing.T) {
+	setup()
+
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "myBindingSecret",
+			Labels: map[string]string{
+				"secretType": "mobile-client-binding-secret",
+			},
+		},
+		Data: map[string][]byte{
+			"appType":  []byte("Android"),
+			"clientId": []byte("myClientId"),
+		},
+	}
+
+	// the PushVariant cannot be stored
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-android-credentials").Return(nil, errors.New("the server is currently unable to handle the request"))
+	kubeHelper.On("deleteSecret", mock.Anything, "myBindingSecret")
+
+	retries := bindingSecretsProcessed.WithLabelValues("android", bindingOutcomeRetry)
+	unsupported := bindingSecretsProcessed.WithLabelValues("unknown", bindingOutcomeUnsupported)
+	retriesBefore, unsupportedBefore := counterValue(t, retries), counterValue(t, unsupported)
+
+	op.handleAddSecret(context.Background(), &secret)
+
+	secret.Data["appType"] = []byte("Windows")
+	op.handleAddSecret(context.Background(), &secret)
+
+	if value := counterValue(t, retries) - retriesBefore; value != 1 {
+		t.Errorf("expected 1 android binding secret to be retried but got %v", value)
+	}
+	if value := counterValue(t, unsupported) - unsupportedBefore; value != 1 {
+		t.Errorf("expected 1 unsupported binding secret but got %v", value)
+	}
+}
+
+func TestUpsClientImpl_endpointLabel(t *testing.T) {
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "http://console.example.org", "http://ups.example.org/rest", nil, 0)
+
+	cases := map[string]string{
+		client.applicationUrl():                         "applications/:id",
+		client.applicationUrl("android"):                "applications/:id/android",
+		client.applicationUrl("ios_token", "myVariant"): "applications/:id/ios_token/:id",
+	}
+	for url, expected := range cases {
+		if label := client.endpointLabel(url); label != expected {
+			t.Errorf("expected endpoint label `%s` for %s but got `%s`", expected, url, label)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package configOperator
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Cleans up after a deleted mobile client: deletes the service bindings of its variants and
+// its config secret, whose finalizer deletes the variants from UPS. Returns an error if the
+// cleanup should be retried.
+func (op ConfigOperator) handleDeletedMobileClient(ctx context.Context, clientId string) error {
+	log.Printf("Mobile client `%s` has been deleted, removing its push configuration", clientId)
+
+	configSecret, err := op.kubeHelper.findMobileClientConfig(ctx, clientId)
+	if err != nil {
+		return errors.Wrap(err, "cannot look up the config secret")
+	}
+
+	bindingIds, err := op.bindingIdsOfMobileClient(ctx, clientId, configSecret)
+	if err != nil {
+		return err
+	}
+
+	// Otherwise the service catalog would provide the binding secrets again
+	err = op.deleteServiceBindings(ctx, bindingIds)
+	if err != nil {
+		return err
+	}
+
+	// Otherwise a sync would create the variants and the config secret again
+	err = op.deletePushVariantsOfMobileClient(ctx, clientId)
+	if err != nil {
+		return err
+	}
+
+	if configSecret == nil {
+		return nil
+	}
+
+	// Secrets created by older versions of the operator have no finalizer that would take care
+	// of the variants
+	if !hasFinalizer(configSecret.ObjectMeta, constants.ConfigSecretFinalizer) {
+		err = op.deleteVariantsOfConfigSecret(ctx, configSecret)
+		if err != nil {
+			return err
+		}
+	}
+
+	op.kubeHelper.deleteSecret(ctx, configSecret.Name)
+	return nil
+}
+
+// Returns the ExternalIDs of the service bindings the variants of a mobile client have been
+// created for, as recorded in the config secret and the PushVariants
+func (op ConfigOperator) bindingIdsOfMobileClient(ctx context.Context, clientId string, configSecret *v1.Secret) (map[string]bool, error) {
+	bindingIds := make(map[string]bool)
+
+	if configSecret != nil {
+		for key, bindingId := range configSecret.Annotations {
+			if strings.HasPrefix(key, "binding/") && bindingId != "" {
+				bindingIds[bindingId] = true
+			}
+		}
+	}
+
+	for _, platform := range op.platforms.all() {
+		pushVariant, err := op.kubeHelper.getPushVariant(ctx, pushVariantName(clientId, platform.getName()))
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot look up the PushVariants")
+		}
+		if pushVariant != nil && pushVariant.Spec.ServiceBindingId != "" {
+			bindingIds[pushVariant.Spec.ServiceBindingId] = true
+		}
+	}
+
+	return bindingIds, nil
+}
+
+// Deletes the PushVariants of a mobile client, which removes their variants once the deletions
+// are handled
+func (op ConfigOperator) deletePushVariantsOfMobileClient(ctx context.Context, clientId string) error {
+	pushVariants, err := op.kubeHelper.listPushVariants(ctx, "clientId="+clientId)
+	if err != nil {
+		return errors.Wrap(err, "cannot list the PushVariants")
+	}
+
+	for _, pushVariant := range pushVariants.Items {
+		if pushVariant.DeletionTimestamp != nil {
+			continue
+		}
+
+		err = op.kubeHelper.deletePushVariant(ctx, pushVariant.Name)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete PushVariant `%s`", pushVariant.Name)
+		}
+		log.Printf("PushVariant `%s` has been deleted", pushVariant.Name)
+	}
+	return nil
+}
+
+// Deletes the service bindings with the given ExternalIDs that still exist
+func (op ConfigOperator) deleteServiceBindings(ctx context.Context, bindingIds map[string]bool) error {
+	if len(bindingIds) == 0 {
+		return nil
+	}
+
+	bindings, err := op.kubeHelper.listServiceBindings(ctx)
+	if err != nil {
+		return errors.Wrap(err, "cannot list the service bindings")
+	}
+
+	for _, binding := range bindings.Items {
+		if !bindingIds[binding.Spec.ExternalID] || binding.DeletionTimestamp != nil {
+			continue
+		}
+
+		err = op.kubeHelper.deleteServiceBinding(ctx, binding.Name)
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete service binding `%s`", binding.Name)
+		}
+		log.Printf("Service binding `%s` has been deleted", binding.Name)
+	}
+	return nil
+}
+
+// Makes the mobile client the owner of a config secret, so that the secret is deleted together
+// with the client. Config secrets created by older versions of the operator have no owner yet.
+func (op ConfigOperator) setConfigSecretOwner(ctx context.Context, configSecret *v1.Secret, clientId string) {
+	if len(configSecret.OwnerReferences) > 0 {
+		return
+	}
+
+	mobileClient, err := op.kubeHelper.getMobileClient(ctx, clientId)
+	if err != nil {
+		log.Printf("Cannot look up mobile client `%s`, keeping config secret `%s` without an owner: %s", clientId, configSecret.Name, err.Error())
+		return
+	}
+	if mobileClient == nil {
+		return
+	}
+
+	configSecret.OwnerReferences = []metav1.OwnerReference{mobileClientOwnerReference(mobileClient)}
+}
@@ -0,0 +1,148 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+func newConfigSecretOfMobileClient() *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mySecretName",
+			Labels:      map[string]string{"clientId": "myClientId", "serviceName": "ups"},
+			Annotations: map[string]string{"binding/android": "myAndroidBindingId"},
+			Finalizers:  []string{"push.aerogear.org/delete-variants"},
+		},
+		Data: map[string][]byte{
+			"config": []byte("{\"android\":{\"variantId\":\"myAndroidVariantId\"}}"),
+		},
+	}
+}
+
+func newServiceBindingList() *v1beta1.ServiceBindingList {
+	return &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "myAndroidBinding"}, Spec: v1beta1.ServiceBindingSpec{ExternalID: "myAndroidBindingId"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "myIOSBinding"}, Spec: v1beta1.ServiceBindingSpec{ExternalID: "myIOSBindingId"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "otherBinding"}, Spec: v1beta1.ServiceBindingSpec{ExternalID: "otherBindingId"}},
+	}}
+}
+
+func TestConfigOperator_handleDeletedMobileClient_deletesBindingsAndConfigSecret(t *testing.T) {
+	setup()
+
+	iosVariant := &push.PushVariant{Spec: push.PushVariantSpec{ServiceBindingId: "myIOSBindingId"}}
+	iosVariant.Name = "myClientId-ios"
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(newConfigSecretOfMobileClient(), nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-ios").Return(iosVariant, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("listPushVariants", mock.Anything, "clientId=myClientId").Return(&push.PushVariantList{Items: []push.PushVariant{*iosVariant}}, nil)
+	kubeHelper.On("deletePushVariant", mock.Anything, "myClientId-ios").Return(nil)
+	kubeHelper.On("listServiceBindings", mock.Anything).Return(newServiceBindingList(), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, mock.Anything).Return(nil)
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName")
+
+	err := op.handleDeletedMobileClient(context.Background(), "myClientId")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "deleteServiceBinding", mock.Anything, "myAndroidBinding")
+	kubeHelper.AssertCalled(t, "deleteServiceBinding", mock.Anything, "myIOSBinding")
+	kubeHelper.AssertNotCalled(t, "deleteServiceBinding", mock.Anything, "otherBinding")
+	kubeHelper.AssertCalled(t, "deletePushVariant", mock.Anything, "myClientId-ios")
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "mySecretName")
+	// the finalizer of the config secret deletes the variants
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_handleDeletedMobileClient_deletesVariantsOfSecretsWithoutFinalizer(t *testing.T) {
+	setup()
+
+	configSecret := newConfigSecretOfMobileClient()
+	configSecret.Finalizers = nil
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("listPushVariants", mock.Anything, mock.Anything).Return(&push.PushVariantList{}, nil)
+	kubeHelper.On("listServiceBindings", mock.Anything).Return(newServiceBindingList(), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, mock.Anything).Return(nil)
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName")
+
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(errUpsCircuitOpen).Once()
+	err := op.handleDeletedMobileClient(context.Background(), "myClientId")
+	if !IsUpsUnavailable(err) {
+		t.Fatalf("expected the mobile client to be handled again later but got %v", err)
+	}
+	kubeHelper.AssertNotCalled(t, "deleteSecret", mock.Anything, mock.Anything)
+
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(nil)
+	err = op.handleDeletedMobileClient(context.Background(), "myClientId")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "mySecretName")
+}
+
+func TestConfigOperator_setConfigSecretOwner(t *testing.T) {
+	setup()
+
+	mobileClient := &mobile.MobileClient{ObjectMeta: metav1.ObjectMeta{Name: "myClientId", UID: "myUID"}}
+	kubeHelper = new(MockKubeHelper)
+	kubeHelper.On("getMobileClient", mock.Anything, "myClientId").Return(mobileClient, nil)
+	op.kubeHelper = kubeHelper
+
+	configSecret := newConfigSecretOfMobileClient()
+	op.setConfigSecretOwner(context.Background(), configSecret, "myClientId")
+
+	if len(configSecret.OwnerReferences) != 1 {
+		t.Fatalf("expected the mobile client to own the config secret but got %v", configSecret.OwnerReferences)
+	}
+	owner := configSecret.OwnerReferences[0]
+	if owner.Kind != "MobileClient" || owner.Name != "myClientId" || owner.UID != "myUID" {
+		t.Errorf("unexpected owner reference %v", owner)
+	}
+}
+
+func TestSecretController_handlesDeletedMobileClients(t *testing.T) {
+	setup()
+
+	mobileClient := mobile.MobileClient{ObjectMeta: metav1.ObjectMeta{Name: "myClientId"}}
+	mobileClientWatcher := watch.NewFake()
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{Items: []mobile.MobileClient{mobileClient}}, &mobile.MobileClient{}, mobileClientWatcher))
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(newConfigSecretOfMobileClient(), nil)
+	kubeHelper.On("getPushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("listPushVariants", mock.Anything, mock.Anything).Return(&push.PushVariantList{}, nil)
+	kubeHelper.On("listServiceBindings", mock.Anything).Return(newServiceBindingList(), nil)
+	kubeHelper.On("deleteServiceBinding", mock.Anything, mock.Anything).Return(nil)
+
+	calls := make(chan string, 10)
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName").Run(func(args mock.Arguments) {
+		calls <- "deleteSecret"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSecretController(*op).run(ctx, ctx.Done())
+
+	mobileClientWatcher.Delete(&mobileClient)
+
+	waitFor(t, calls, "deleteSecret")
+	cancel()
+
+	kubeHelper.AssertCalled(t, "deleteServiceBinding", mock.Anything, "myAndroidBinding")
+}
@@ -2,6 +2,8 @@
 
 package configOperator
 
+import context "context"
+import v1alpha1 "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
 import mock "github.com/stretchr/testify/mock"
 
 // MockAnnotationHelper is an autogenerated mock type for the AnnotationHelper type
@@ -9,12 +11,22 @@ type MockAnnotationHelper struct {
 	mock.Mock
 }
 
-// addAnnotationToMobileClient provides a mock function with given fields: clientId, upsUrl, pushApplicationId, pushApplicationName, appType, variantUrl, serviceInstanceName
-func (_m *MockAnnotationHelper) addAnnotationToMobileClient(clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantUrl string, serviceInstanceName string) {
-	_m.Called(clientId, upsUrl, pushApplicationId, pushApplicationName, appType, variantUrl, serviceInstanceName)
+// addAnnotationToMobileClient provides a mock function with given fields: ctx, clientId, upsUrl, pushApplicationId, pushApplicationName, appType, variantUrl, serviceInstanceName
+func (_m *MockAnnotationHelper) addAnnotationToMobileClient(ctx context.Context, clientId string, upsUrl string, pushApplicationId string, pushApplicationName string, appType string, variantUrl string, serviceInstanceName string) {
+	_m.Called(ctx, clientId, upsUrl, pushApplicationId, pushApplicationName, appType, variantUrl, serviceInstanceName)
 }
 
-// removeAnnotationFromMobileClient provides a mock function with given fields: clientId, appType, serviceInstanceName
-func (_m *MockAnnotationHelper) removeAnnotationFromMobileClient(clientId string, appType string, serviceInstanceName string) {
-	_m.Called(clientId, appType, serviceInstanceName)
+// removeAnnotationFromMobileClient provides a mock function with given fields: ctx, clientId, appType, serviceInstanceName
+func (_m *MockAnnotationHelper) removeAnnotationFromMobileClient(ctx context.Context, clientId string, appType string, serviceInstanceName string) {
+	_m.Called(ctx, clientId, appType, serviceInstanceName)
+}
+
+// removePushServiceStatus provides a mock function with given fields: ctx, clientId
+func (_m *MockAnnotationHelper) removePushServiceStatus(ctx context.Context, clientId string) {
+	_m.Called(ctx, clientId)
+}
+
+// setPushServiceStatus provides a mock function with given fields: ctx, clientId, service
+func (_m *MockAnnotationHelper) setPushServiceStatus(ctx context.Context, clientId string, service v1alpha1.MobileClientService) {
+	_m.Called(ctx, clientId, service)
 }
@@ -2,43 +2,115 @@
 
 package configOperator
 
+import context "context"
+import mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+import v1alpha1 "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+import v1beta1 "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
 import mock "github.com/stretchr/testify/mock"
 import v1 "k8s.io/api/core/v1"
-import watch "k8s.io/apimachinery/pkg/watch"
+import cache "k8s.io/client-go/tools/cache"
+import time "time"
 
 // MockKubeHelper is an autogenerated mock type for the KubeHelper type
 type MockKubeHelper struct {
 	mock.Mock
 }
 
-// createClientConfigSecret provides a mock function with given fields: clientId, serviceInstanceName, serviceInstanceId, pushAppId
-func (_m *MockKubeHelper) createClientConfigSecret(clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) *v1.Secret {
-	ret := _m.Called(clientId, serviceInstanceName, serviceInstanceId, pushAppId)
+// createClientConfigSecret provides a mock function with given fields: ctx, clientId, serviceInstanceName, serviceInstanceId, pushAppId
+func (_m *MockKubeHelper) createClientConfigSecret(ctx context.Context, clientId string, serviceInstanceName string, serviceInstanceId string, pushAppId string) (*v1.Secret, error) {
+	ret := _m.Called(ctx, clientId, serviceInstanceName, serviceInstanceId, pushAppId)
 
 	var r0 *v1.Secret
-	if rf, ok := ret.Get(0).(func(string, string, string, string) *v1.Secret); ok {
-		r0 = rf(clientId, serviceInstanceName, serviceInstanceId, pushAppId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *v1.Secret); ok {
+		r0 = rf(ctx, clientId, serviceInstanceName, serviceInstanceId, pushAppId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.Secret)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, clientId, serviceInstanceName, serviceInstanceId, pushAppId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// createPushVariant provides a mock function with given fields: ctx, pushVariant
+func (_m *MockKubeHelper) createPushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error) {
+	ret := _m.Called(ctx, pushVariant)
+
+	var r0 *v1alpha1.PushVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.PushVariant) *v1alpha1.PushVariant); ok {
+		r0 = rf(ctx, pushVariant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1alpha1.PushVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.PushVariant) error); ok {
+		r1 = rf(ctx, pushVariant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// createSecret provides a mock function with given fields: ctx, secret
+func (_m *MockKubeHelper) createSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	ret := _m.Called(ctx, secret)
+
+	var r0 *v1.Secret
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.Secret) *v1.Secret); ok {
+		r0 = rf(ctx, secret)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Secret)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.Secret) error); ok {
+		r1 = rf(ctx, secret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// deletePushVariant provides a mock function with given fields: ctx, name
+func (_m *MockKubeHelper) deletePushVariant(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
 	return r0
 }
 
-// deleteSecret provides a mock function with given fields: name
-func (_m *MockKubeHelper) deleteSecret(name string) {
-	_m.Called(name)
+// deleteSecret provides a mock function with given fields: ctx, name
+func (_m *MockKubeHelper) deleteSecret(ctx context.Context, name string) {
+	_m.Called(ctx, name)
 }
 
-// deleteServiceBinding provides a mock function with given fields: bindingName
-func (_m *MockKubeHelper) deleteServiceBinding(bindingName string) error {
-	ret := _m.Called(bindingName)
+// deleteServiceBinding provides a mock function with given fields: ctx, bindingName
+func (_m *MockKubeHelper) deleteServiceBinding(ctx context.Context, bindingName string) error {
+	ret := _m.Called(ctx, bindingName)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(bindingName)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, bindingName)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -46,36 +118,114 @@ func (_m *MockKubeHelper) deleteServiceBinding(bindingName string) error {
 	return r0
 }
 
-// findMobileClientConfig provides a mock function with given fields: clientId
-func (_m *MockKubeHelper) findMobileClientConfig(clientId string) *v1.Secret {
-	ret := _m.Called(clientId)
+// findMobileClientConfig provides a mock function with given fields: ctx, clientId
+func (_m *MockKubeHelper) findMobileClientConfig(ctx context.Context, clientId string) (*v1.Secret, error) {
+	ret := _m.Called(ctx, clientId)
 
 	var r0 *v1.Secret
-	if rf, ok := ret.Get(0).(func(string) *v1.Secret); ok {
-		r0 = rf(clientId)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1.Secret); ok {
+		r0 = rf(ctx, clientId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.Secret)
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, clientId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getMobileClient provides a mock function with given fields: ctx, name
+func (_m *MockKubeHelper) getMobileClient(ctx context.Context, name string) (*mobile.MobileClient, error) {
+	ret := _m.Called(ctx, name)
+
+	var r0 *mobile.MobileClient
+	if rf, ok := ret.Get(0).(func(context.Context, string) *mobile.MobileClient); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mobile.MobileClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getPushVariant provides a mock function with given fields: ctx, name
+func (_m *MockKubeHelper) getPushVariant(ctx context.Context, name string) (*v1alpha1.PushVariant, error) {
+	ret := _m.Called(ctx, name)
+
+	var r0 *v1alpha1.PushVariant
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1alpha1.PushVariant); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1alpha1.PushVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getSecret provides a mock function with given fields: ctx, name
+func (_m *MockKubeHelper) getSecret(ctx context.Context, name string) (*v1.Secret, error) {
+	ret := _m.Called(ctx, name)
+
+	var r0 *v1.Secret
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1.Secret); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.Secret)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// getServiceBindingNameByID provides a mock function with given fields: bindingId
-func (_m *MockKubeHelper) getServiceBindingNameByID(bindingId string) (string, error) {
-	ret := _m.Called(bindingId)
+// getServiceBindingByID provides a mock function with given fields: ctx, bindingId
+func (_m *MockKubeHelper) getServiceBindingByID(ctx context.Context, bindingId string) (*v1beta1.ServiceBinding, error) {
+	ret := _m.Called(ctx, bindingId)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(bindingId)
+	var r0 *v1beta1.ServiceBinding
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1beta1.ServiceBinding); ok {
+		r0 = rf(ctx, bindingId)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1beta1.ServiceBinding)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(bindingId)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, bindingId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -83,13 +233,36 @@ func (_m *MockKubeHelper) getServiceBindingNameByID(bindingId string) (string, e
 	return r0, r1
 }
 
-// listSecrets provides a mock function with given fields: selector
-func (_m *MockKubeHelper) listSecrets(selector string) (*v1.SecretList, error) {
-	ret := _m.Called(selector)
+// listPushVariants provides a mock function with given fields: ctx, selector
+func (_m *MockKubeHelper) listPushVariants(ctx context.Context, selector string) (*v1alpha1.PushVariantList, error) {
+	ret := _m.Called(ctx, selector)
+
+	var r0 *v1alpha1.PushVariantList
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1alpha1.PushVariantList); ok {
+		r0 = rf(ctx, selector)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1alpha1.PushVariantList)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, selector)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// listSecrets provides a mock function with given fields: ctx, selector
+func (_m *MockKubeHelper) listSecrets(ctx context.Context, selector string) (*v1.SecretList, error) {
+	ret := _m.Called(ctx, selector)
 
 	var r0 *v1.SecretList
-	if rf, ok := ret.Get(0).(func(string) *v1.SecretList); ok {
-		r0 = rf(selector)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1.SecretList); ok {
+		r0 = rf(ctx, selector)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.SecretList)
@@ -97,8 +270,134 @@ func (_m *MockKubeHelper) listSecrets(selector string) (*v1.SecretList, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(selector)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, selector)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// listServiceBindings provides a mock function with given fields: ctx
+func (_m *MockKubeHelper) listServiceBindings(ctx context.Context) (*v1beta1.ServiceBindingList, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *v1beta1.ServiceBindingList
+	if rf, ok := ret.Get(0).(func(context.Context) *v1beta1.ServiceBindingList); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1beta1.ServiceBindingList)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// newMobileClientInformer provides a mock function with given fields: resync
+func (_m *MockKubeHelper) newMobileClientInformer(resync time.Duration) cache.SharedIndexInformer {
+	ret := _m.Called(resync)
+
+	var r0 cache.SharedIndexInformer
+	if rf, ok := ret.Get(0).(func(time.Duration) cache.SharedIndexInformer); ok {
+		r0 = rf(resync)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cache.SharedIndexInformer)
+		}
+	}
+
+	return r0
+}
+
+// newPushApplicationInformer provides a mock function with given fields: resync
+func (_m *MockKubeHelper) newPushApplicationInformer(resync time.Duration) cache.SharedIndexInformer {
+	ret := _m.Called(resync)
+
+	var r0 cache.SharedIndexInformer
+	if rf, ok := ret.Get(0).(func(time.Duration) cache.SharedIndexInformer); ok {
+		r0 = rf(resync)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cache.SharedIndexInformer)
+		}
+	}
+
+	return r0
+}
+
+// newPushVariantInformer provides a mock function with given fields: resync
+func (_m *MockKubeHelper) newPushVariantInformer(resync time.Duration) cache.SharedIndexInformer {
+	ret := _m.Called(resync)
+
+	var r0 cache.SharedIndexInformer
+	if rf, ok := ret.Get(0).(func(time.Duration) cache.SharedIndexInformer); ok {
+		r0 = rf(resync)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cache.SharedIndexInformer)
+		}
+	}
+
+	return r0
+}
+
+// newSecretInformer provides a mock function with given fields: selector, resync
+func (_m *MockKubeHelper) newSecretInformer(selector string, resync time.Duration) cache.SharedIndexInformer {
+	ret := _m.Called(selector, resync)
+
+	var r0 cache.SharedIndexInformer
+	if rf, ok := ret.Get(0).(func(string, time.Duration) cache.SharedIndexInformer); ok {
+		r0 = rf(selector, resync)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cache.SharedIndexInformer)
+		}
+	}
+
+	return r0
+}
+
+// newServiceBindingInformer provides a mock function with given fields: resync
+func (_m *MockKubeHelper) newServiceBindingInformer(resync time.Duration) cache.SharedIndexInformer {
+	ret := _m.Called(resync)
+
+	var r0 cache.SharedIndexInformer
+	if rf, ok := ret.Get(0).(func(time.Duration) cache.SharedIndexInformer); ok {
+		r0 = rf(resync)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(cache.SharedIndexInformer)
+		}
+	}
+
+	return r0
+}
+
+// updatePushApplication provides a mock function with given fields: ctx, pushApplication
+func (_m *MockKubeHelper) updatePushApplication(ctx context.Context, pushApplication *v1alpha1.PushApplication) (*v1alpha1.PushApplication, error) {
+	ret := _m.Called(ctx, pushApplication)
+
+	var r0 *v1alpha1.PushApplication
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.PushApplication) *v1alpha1.PushApplication); ok {
+		r0 = rf(ctx, pushApplication)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1alpha1.PushApplication)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.PushApplication) error); ok {
+		r1 = rf(ctx, pushApplication)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -106,22 +405,22 @@ func (_m *MockKubeHelper) listSecrets(selector string) (*v1.SecretList, error) {
 	return r0, r1
 }
 
-// startSecretWatch provides a mock function with given fields:
-func (_m *MockKubeHelper) startSecretWatch() (watch.Interface, error) {
-	ret := _m.Called()
+// updatePushVariant provides a mock function with given fields: ctx, pushVariant
+func (_m *MockKubeHelper) updatePushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) (*v1alpha1.PushVariant, error) {
+	ret := _m.Called(ctx, pushVariant)
 
-	var r0 watch.Interface
-	if rf, ok := ret.Get(0).(func() watch.Interface); ok {
-		r0 = rf()
+	var r0 *v1alpha1.PushVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.PushVariant) *v1alpha1.PushVariant); ok {
+		r0 = rf(ctx, pushVariant)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(watch.Interface)
+			r0 = ret.Get(0).(*v1alpha1.PushVariant)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.PushVariant) error); ok {
+		r1 = rf(ctx, pushVariant)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -129,13 +428,13 @@ func (_m *MockKubeHelper) startSecretWatch() (watch.Interface, error) {
 	return r0, r1
 }
 
-// updateSecret provides a mock function with given fields: secret
-func (_m *MockKubeHelper) updateSecret(secret *v1.Secret) (*v1.Secret, error) {
-	ret := _m.Called(secret)
+// updateSecret provides a mock function with given fields: ctx, secret
+func (_m *MockKubeHelper) updateSecret(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	ret := _m.Called(ctx, secret)
 
 	var r0 *v1.Secret
-	if rf, ok := ret.Get(0).(func(*v1.Secret) *v1.Secret); ok {
-		r0 = rf(secret)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.Secret) *v1.Secret); ok {
+		r0 = rf(ctx, secret)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.Secret)
@@ -143,8 +442,8 @@ func (_m *MockKubeHelper) updateSecret(secret *v1.Secret) (*v1.Secret, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*v1.Secret) error); ok {
-		r1 = rf(secret)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.Secret) error); ok {
+		r1 = rf(ctx, secret)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -2,6 +2,7 @@
 
 package configOperator
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 
 // MockUpsClient is an autogenerated mock type for the UpsClient type
@@ -9,66 +10,172 @@ type MockUpsClient struct {
 	mock.Mock
 }
 
-// createAndroidVariant provides a mock function with given fields: variant
-func (_m *MockUpsClient) createAndroidVariant(variant *AndroidVariant) (bool, *AndroidVariant) {
-	ret := _m.Called(variant)
+// createAndroidVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) createAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
+	ret := _m.Called(ctx, variant)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(*AndroidVariant) bool); ok {
-		r0 = rf(variant)
+	var r0 *AndroidVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *AndroidVariant) *AndroidVariant); ok {
+		r0 = rf(ctx, variant)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*AndroidVariant)
+		}
 	}
 
-	var r1 *AndroidVariant
-	if rf, ok := ret.Get(1).(func(*AndroidVariant) *AndroidVariant); ok {
-		r1 = rf(variant)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *AndroidVariant) error); ok {
+		r1 = rf(ctx, variant)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(*AndroidVariant)
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// createApplication provides a mock function with given fields: ctx, application
+func (_m *MockUpsClient) createApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	ret := _m.Called(ctx, application)
+
+	var r0 *PushApplication
+	if rf, ok := ret.Get(0).(func(context.Context, *PushApplication) *PushApplication); ok {
+		r0 = rf(ctx, application)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PushApplication)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *PushApplication) error); ok {
+		r1 = rf(ctx, application)
+	} else {
+		r1 = ret.Error(1)
+	}
+
 	return r0, r1
 }
 
-// createIOSVariant provides a mock function with given fields: variant
-func (_m *MockUpsClient) createIOSVariant(variant *IOSVariant) (bool, *IOSVariant) {
-	ret := _m.Called(variant)
+// createIOSTokenVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) createIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	ret := _m.Called(ctx, variant)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(*IOSVariant) bool); ok {
-		r0 = rf(variant)
+	var r0 *IOSTokenVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *IOSTokenVariant) *IOSTokenVariant); ok {
+		r0 = rf(ctx, variant)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*IOSTokenVariant)
+		}
 	}
 
-	var r1 *IOSVariant
-	if rf, ok := ret.Get(1).(func(*IOSVariant) *IOSVariant); ok {
-		r1 = rf(variant)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *IOSTokenVariant) error); ok {
+		r1 = rf(ctx, variant)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(*IOSVariant)
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// createIOSVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) createIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *IOSVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *IOSVariant) *IOSVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*IOSVariant)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *IOSVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
 	return r0, r1
 }
 
-// deleteVariant provides a mock function with given fields: platform, variantId
-func (_m *MockUpsClient) deleteVariant(platform string, variantId string) bool {
-	ret := _m.Called(platform, variantId)
+// createWebPushVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) createWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	ret := _m.Called(ctx, variant)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
-		r0 = rf(platform, variantId)
+	var r0 *WebPushVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *WebPushVariant) *WebPushVariant); ok {
+		r0 = rf(ctx, variant)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WebPushVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *WebPushVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// deleteApplication provides a mock function with given fields: ctx, applicationId
+func (_m *MockUpsClient) deleteApplication(ctx context.Context, applicationId string) error {
+	ret := _m.Called(ctx, applicationId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, applicationId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// deleteVariant provides a mock function with given fields: ctx, platform, variantId
+func (_m *MockUpsClient) deleteVariant(ctx context.Context, platform string, variantId string) error {
+	ret := _m.Called(ctx, platform, variantId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, platform, variantId)
+	} else {
+		r0 = ret.Error(0)
 	}
 
 	return r0
 }
 
+// getApplication provides a mock function with given fields: ctx, applicationId
+func (_m *MockUpsClient) getApplication(ctx context.Context, applicationId string) (*PushApplication, error) {
+	ret := _m.Called(ctx, applicationId)
+
+	var r0 *PushApplication
+	if rf, ok := ret.Get(0).(func(context.Context, string) *PushApplication); ok {
+		r0 = rf(ctx, applicationId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PushApplication)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, applicationId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // getApplicationId provides a mock function with given fields:
 func (_m *MockUpsClient) getApplicationId() string {
 	ret := _m.Called()
@@ -97,20 +204,20 @@ func (_m *MockUpsClient) getBaseUrl() string {
 	return r0
 }
 
-// getPushApplicationName provides a mock function with given fields:
-func (_m *MockUpsClient) getPushApplicationName() (string, error) {
-	ret := _m.Called()
+// getPushApplicationName provides a mock function with given fields: ctx
+func (_m *MockUpsClient) getPushApplicationName(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func() string); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -132,13 +239,59 @@ func (_m *MockUpsClient) getServiceInstanceId() string {
 	return r0
 }
 
-// getVariants provides a mock function with given fields:
-func (_m *MockUpsClient) getVariants() ([]Variant, error) {
-	ret := _m.Called()
+// getVariant provides a mock function with given fields: ctx, platform, variantId
+func (_m *MockUpsClient) getVariant(ctx context.Context, platform string, variantId string) (*Variant, error) {
+	ret := _m.Called(ctx, platform, variantId)
+
+	var r0 *Variant
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Variant); ok {
+		r0 = rf(ctx, platform, variantId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Variant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, platform, variantId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getVariants provides a mock function with given fields: ctx
+func (_m *MockUpsClient) getVariants(ctx context.Context) ([]Variant, error) {
+	ret := _m.Called(ctx)
 
 	var r0 []Variant
-	if rf, ok := ret.Get(0).(func() []Variant); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) []Variant); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]Variant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getVariantsForPlatform provides a mock function with given fields: ctx, platform
+func (_m *MockUpsClient) getVariantsForPlatform(ctx context.Context, platform string) ([]Variant, error) {
+	ret := _m.Called(ctx, platform)
+
+	var r0 []Variant
+	if rf, ok := ret.Get(0).(func(context.Context, string) []Variant); ok {
+		r0 = rf(ctx, platform)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]Variant)
@@ -146,8 +299,8 @@ func (_m *MockUpsClient) getVariants() ([]Variant, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, platform)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -155,18 +308,154 @@ func (_m *MockUpsClient) getVariants() ([]Variant, error) {
 	return r0, r1
 }
 
-// hasAndroidVariant provides a mock function with given fields: key
-func (_m *MockUpsClient) hasAndroidVariant(key string) *AndroidVariant {
-	ret := _m.Called(key)
+// hasAndroidVariant provides a mock function with given fields: ctx, key
+func (_m *MockUpsClient) hasAndroidVariant(ctx context.Context, key string) (*AndroidVariant, error) {
+	ret := _m.Called(ctx, key)
 
 	var r0 *AndroidVariant
-	if rf, ok := ret.Get(0).(func(string) *AndroidVariant); ok {
-		r0 = rf(key)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *AndroidVariant); ok {
+		r0 = rf(ctx, key)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*AndroidVariant)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// isAvailable provides a mock function with given fields:
+func (_m *MockUpsClient) isAvailable() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
 	return r0
 }
+
+// updateAndroidVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) updateAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *AndroidVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *AndroidVariant) *AndroidVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*AndroidVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *AndroidVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// updateApplication provides a mock function with given fields: ctx, application
+func (_m *MockUpsClient) updateApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	ret := _m.Called(ctx, application)
+
+	var r0 *PushApplication
+	if rf, ok := ret.Get(0).(func(context.Context, *PushApplication) *PushApplication); ok {
+		r0 = rf(ctx, application)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PushApplication)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *PushApplication) error); ok {
+		r1 = rf(ctx, application)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// updateIOSTokenVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) updateIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *IOSTokenVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *IOSTokenVariant) *IOSTokenVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*IOSTokenVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *IOSTokenVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// updateIOSVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) updateIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *IOSVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *IOSVariant) *IOSVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*IOSVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *IOSVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// updateWebPushVariant provides a mock function with given fields: ctx, variant
+func (_m *MockUpsClient) updateWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	var r0 *WebPushVariant
+	if rf, ok := ret.Get(0).(func(context.Context, *WebPushVariant) *WebPushVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WebPushVariant)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *WebPushVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -2,6 +2,7 @@
 
 package configOperator
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 
 // MockUpsClientProvider is an autogenerated mock type for the UpsClientProvider type
@@ -9,13 +10,29 @@ type MockUpsClientProvider struct {
 	mock.Mock
 }
 
-// getPushClient provides a mock function with given fields:
-func (_m *MockUpsClientProvider) getPushClient() UpsClient {
-	ret := _m.Called()
+// getAdminClient provides a mock function with given fields: ctx
+func (_m *MockUpsClientProvider) getAdminClient(ctx context.Context) UpsClient {
+	ret := _m.Called(ctx)
 
 	var r0 UpsClient
-	if rf, ok := ret.Get(0).(func() UpsClient); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) UpsClient); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(UpsClient)
+		}
+	}
+
+	return r0
+}
+
+// getPushClient provides a mock function with given fields: ctx
+func (_m *MockUpsClientProvider) getPushClient(ctx context.Context) UpsClient {
+	ret := _m.Called(ctx)
+
+	var r0 UpsClient
+	if rf, ok := ret.Get(0).(func(context.Context) UpsClient); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(UpsClient)
@@ -24,3 +41,38 @@ func (_m *MockUpsClientProvider) getPushClient() UpsClient {
 
 	return r0
 }
+
+// getStatus provides a mock function with given fields:
+func (_m *MockUpsClientProvider) getStatus() UpsClientStatus {
+	ret := _m.Called()
+
+	var r0 UpsClientStatus
+	if rf, ok := ret.Get(0).(func() UpsClientStatus); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(UpsClientStatus)
+	}
+
+	return r0
+}
+
+// upsSecretExists provides a mock function with given fields: ctx
+func (_m *MockUpsClientProvider) upsSecretExists(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
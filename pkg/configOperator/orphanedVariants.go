@@ -0,0 +1,176 @@
+package configOperator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// What the operator does with variants of the push application that no config secret refers to
+type OrphanedVariantPolicy string
+
+const (
+	// Orphaned variants are logged, counted and recorded as an event on their mobile client
+	OrphanedVariantPolicyReport OrphanedVariantPolicy = "report"
+	// Orphaned variants are written to a config secret again, with the credentials of their PushVariant
+	OrphanedVariantPolicyAdopt OrphanedVariantPolicy = "adopt"
+	// Orphaned variants are deleted from UPS
+	OrphanedVariantPolicyDelete OrphanedVariantPolicy = "delete"
+)
+
+// Number of polls in a row a variant has to be orphaned in before it is handled. The controller
+// might be about to write the config secret of a variant it just created.
+const orphanedVariantPolls = 2
+
+func ParseOrphanedVariantPolicy(value string) (OrphanedVariantPolicy, error) {
+	policy := OrphanedVariantPolicy(value)
+	switch policy {
+	case OrphanedVariantPolicyReport, OrphanedVariantPolicyAdopt, OrphanedVariantPolicyDelete:
+		return policy, nil
+	}
+	return "", errors.Errorf("invalid orphaned variant policy `%s`, expected `%s`, `%s` or `%s`", value,
+		OrphanedVariantPolicyReport, OrphanedVariantPolicyAdopt, OrphanedVariantPolicyDelete)
+}
+
+type orphanedVariant struct {
+	platform Platform
+	variant  Variant
+}
+
+// Looks for variants of the push application that no config secret refers to. They are left
+// behind when the operator stops between creating a variant and writing the config secret, or
+// when a config secret is deleted by hand. Depending on the policy they are reported, adopted
+// or deleted.
+func (op ConfigOperator) handleOrphanedVariants(ctx context.Context, pushClient UpsClient, secrets []v1.Secret) {
+	// Variants of config secrets that are being deleted are removed by the finalizer
+	knownIds := make(map[string]bool)
+	for i := range secrets {
+		for _, variantId := range op.variantIdsOfConfigSecret(&secrets[i]) {
+			knownIds[variantId] = true
+		}
+	}
+
+	var orphans []orphanedVariant
+	var orphanIds []string
+	for _, platform := range op.platforms.all() {
+		variants, err := platform.listVariants(ctx, pushClient)
+		if err != nil {
+			log.Printf("Cannot look for orphaned %s variants: %s", platform.getName(), err.Error())
+			return
+		}
+		for _, variant := range variants {
+			if !knownIds[variant.VariantID] {
+				orphans = append(orphans, orphanedVariant{platform: platform, variant: variant})
+				orphanIds = append(orphanIds, variant.VariantID)
+			}
+		}
+	}
+	op.orphanedVariants.record(orphanIds)
+
+	confirmed := 0
+	for _, orphan := range orphans {
+		polls := op.orphanedVariants.get(orphan.variant.VariantID)
+		if polls < orphanedVariantPolls {
+			continue
+		}
+		confirmed++
+		op.handleOrphanedVariant(ctx, pushClient, orphan.platform, orphan.variant, polls == orphanedVariantPolls)
+	}
+	orphanedVariantsFound.Set(float64(confirmed))
+}
+
+// Applies the policy to an orphaned variant. Adopting and deleting is retried with every poll
+// until it succeeds, reports are only made when the variant is found.
+func (op ConfigOperator) handleOrphanedVariant(ctx context.Context, pushClient UpsClient, platform Platform, variant Variant, found bool) {
+	clientId := variant.Name
+	if found {
+		log.Printf("The %s variant %s of client `%s` exists in UPS but not in any config secret", platform.getName(), variant.VariantID, clientId)
+	}
+
+	switch op.orphanedVariantPolicy {
+	case OrphanedVariantPolicyAdopt:
+		err := op.adoptOrphanedVariant(ctx, pushClient, platform, variant)
+		if err != nil {
+			log.Printf("Cannot adopt the %s variant %s: %s", platform.getName(), variant.VariantID, err.Error())
+			return
+		}
+		orphanedVariantsHandled.WithLabelValues(string(OrphanedVariantPolicyAdopt)).Inc()
+		op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeNormal, eventReasonOrphanedVariantAdopted,
+			fmt.Sprintf("The %s variant %s had no config secret, it has been written again", platform.getName(), variant.VariantID))
+
+	case OrphanedVariantPolicyDelete:
+		err := platform.deleteVariant(ctx, pushClient, &BindingSecret{}, variant.VariantID)
+		if err != nil && !IsUpsNotFound(err) {
+			log.Printf("Cannot delete the %s variant %s: %s", platform.getName(), variant.VariantID, err.Error())
+			return
+		}
+		orphanedVariantsHandled.WithLabelValues(string(OrphanedVariantPolicyDelete)).Inc()
+		op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeWarning, eventReasonOrphanedVariantDeleted,
+			fmt.Sprintf("The %s variant %s had no config secret, it has been deleted from UPS", platform.getName(), variant.VariantID))
+
+	default:
+		if found {
+			op.eventHelper.mobileClientEvent(ctx, clientId, v1.EventTypeWarning, eventReasonOrphanedVariantFound,
+				fmt.Sprintf("The %s variant %s exists in UPS but has no config secret", platform.getName(), variant.VariantID))
+		}
+	}
+}
+
+// Handles the PushVariant of the client and platform again, like a sync does. The variant is
+// found by its name, the client ID, and written to a config secret.
+func (op ConfigOperator) adoptOrphanedVariant(ctx context.Context, pushClient UpsClient, platform Platform, variant Variant) error {
+	name := pushVariantName(variant.Name, platform.getName())
+	pushVariant, err := op.kubeHelper.getPushVariant(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "cannot look up PushVariant `%s`", name)
+	}
+	if pushVariant == nil {
+		return errors.Errorf("there is no PushVariant `%s` with the credentials of the variant", name)
+	}
+
+	// The PushVariant already has another variant, this one is a duplicate
+	if variantId := pushVariant.Status.VariantId; variantId != "" && variantId != variant.VariantID {
+		return errors.Errorf("PushVariant `%s` belongs to variant %s", name, variantId)
+	}
+
+	// The sync would adopt the first variant with the name of the client
+	adoptable, err := platform.findVariant(ctx, pushClient, variant.Name)
+	if err != nil {
+		return errors.Wrap(err, "cannot look up the variants of the client")
+	}
+	if adoptable == nil || adoptable.VariantID != variant.VariantID {
+		return errors.Errorf("the client has several %s variants", platform.getName())
+	}
+
+	credentials, err := op.kubeHelper.getSecret(ctx, pushVariant.Spec.SecretRef.Name)
+	if err != nil {
+		return errors.Wrap(err, "cannot read the credentials")
+	}
+	if credentials == nil {
+		return errors.Errorf("the credentials secret `%s` does not exist", pushVariant.Spec.SecretRef.Name)
+	}
+
+	variantId, configSecretName, err := op.handleVariant(ctx, platform, bindingSecretOfPushVariant(pushVariant, credentials))
+	if err != nil {
+		return err
+	}
+	return op.setPushVariantStatus(ctx, pushVariant, variantId, configSecretName, nil)
+}
+
+// Returns the IDs of the variants in the config of a config secret
+func (op ConfigOperator) variantIdsOfConfigSecret(configSecret *v1.Secret) []string {
+	var config map[string]json.RawMessage
+	json.Unmarshal(configSecret.Data["config"], &config)
+
+	var variantIds []string
+	for _, platformConfig := range config {
+		if variantId := op.getVariantIdFromConfig(string(platformConfig)); variantId != "" {
+			variantIds = append(variantIds, variantId)
+		}
+	}
+	return variantIds
+}
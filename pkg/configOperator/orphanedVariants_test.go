@@ -0,0 +1,166 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+func newOrphanedVariantSecrets() []v1.Secret {
+	return []v1.Secret{{
+		ObjectMeta: metav1.ObjectMeta{Name: "mySecretName"},
+		Data: map[string][]byte{
+			"config": []byte("{\"android\":{\"variantId\":\"myKnownVariantId\"}}"),
+		},
+	}}
+}
+
+func setupOrphanedVariants(policy OrphanedVariantPolicy) {
+	setup()
+	op.orphanedVariantPolicy = policy
+
+	pushClient.On("getVariantsForPlatform", mock.Anything, "android").Return([]Variant{
+		{Name: "otherClientId", VariantID: "myKnownVariantId"},
+		{Name: "myClientId", VariantID: "myOrphanedVariantId"},
+	}, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+}
+
+func TestConfigOperator_handleOrphanedVariants_reportsOrphansOfSeveralPolls(t *testing.T) {
+	setupOrphanedVariants(OrphanedVariantPolicyReport)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	eventHelper.AssertNotCalled(t, "mobileClientEvent", mock.Anything, mock.Anything, mock.Anything, "OrphanedVariantFound", mock.Anything)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	eventHelper.AssertNumberOfCalls(t, "mobileClientEvent", 1)
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeWarning, "OrphanedVariantFound", mock.Anything)
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_handleOrphanedVariants_deletesOrphans(t *testing.T) {
+	setupOrphanedVariants(OrphanedVariantPolicyDelete)
+	pushClient.On("deleteVariant", mock.Anything, "android", "myOrphanedVariantId").Return(nil)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	pushClient.AssertCalled(t, "deleteVariant", mock.Anything, "android", "myOrphanedVariantId")
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, "android", "myKnownVariantId")
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeWarning, "OrphanedVariantDeleted", mock.Anything)
+}
+
+func TestConfigOperator_handleOrphanedVariants_startsOverWhenTheConfigSecretIsWritten(t *testing.T) {
+	setupOrphanedVariants(OrphanedVariantPolicyDelete)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+
+	// the controller wrote the config secret in between
+	secrets := newOrphanedVariantSecrets()
+	secrets[0].Data["config"] = []byte("{\"android\":{\"variantId\":\"myKnownVariantId\"},\"ios\":{\"variantId\":\"myOrphanedVariantId\"}}")
+	op.handleOrphanedVariants(context.Background(), pushClient, secrets)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_handleOrphanedVariants_adoptsOrphansWithPushVariant(t *testing.T) {
+	setupOrphanedVariants(OrphanedVariantPolicyAdopt)
+
+	pushVariant := &push.PushVariant{
+		Spec: push.PushVariantSpec{
+			Platform:            "android",
+			ClientId:            "myClientId",
+			SecretRef:           v1.LocalObjectReference{Name: "myClientId-android-credentials"},
+			ServiceBindingId:    "myServiceBindingId",
+			ServiceInstanceName: "myServiceInstanceName",
+		},
+	}
+	pushVariant.Name = "myClientId-android"
+	credentials := &v1.Secret{Data: map[string][]byte{
+		"googleKey":     []byte("myGoogleKey"),
+		"projectNumber": []byte("myProjectNumber"),
+	}}
+	configSecret := &v1.Secret{Data: map[string][]byte{"config": []byte("{}")}}
+	configSecret.Name = "myNewSecretName"
+	configSecret.Annotations = map[string]string{}
+
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(pushVariant, nil)
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-android-credentials").Return(credentials, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("updateAndroidVariant", mock.Anything, mock.Anything).Return(&AndroidVariant{
+		ProjectNumber: "myProjectNumber",
+		Variant:       Variant{Name: "myClientId", VariantID: "myOrphanedVariantId", Secret: "myVariantSecret"},
+	}, nil)
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", mock.Anything, mock.Anything, mock.Anything, "android", "myOrphanedVariantId", "myServiceInstanceName")
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+
+	pushClient.AssertCalled(t, "updateAndroidVariant", mock.Anything, mock.MatchedBy(func(variant *AndroidVariant) bool {
+		return variant.VariantID == "myOrphanedVariantId" && variant.GoogleKey == "myGoogleKey"
+	}))
+	kubeHelper.AssertCalled(t, "updatePushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return pushVariant.Status.VariantId == "myOrphanedVariantId" && pushVariant.Status.ConfigSecretName == "myNewSecretName"
+	}))
+	eventHelper.AssertCalled(t, "mobileClientEvent", mock.Anything, "myClientId", v1.EventTypeNormal, "OrphanedVariantAdopted", mock.Anything)
+}
+
+func TestConfigOperator_handleOrphanedVariants_keepsOrphansWithoutPushVariant(t *testing.T) {
+	setupOrphanedVariants(OrphanedVariantPolicyAdopt)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+	op.handleOrphanedVariants(context.Background(), pushClient, newOrphanedVariantSecrets())
+
+	kubeHelper.AssertCalled(t, "getPushVariant", mock.Anything, "myClientId-android")
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+	eventHelper.AssertNotCalled(t, "mobileClientEvent", mock.Anything, mock.Anything, mock.Anything, "OrphanedVariantAdopted", mock.Anything)
+}
+
+func TestParseOrphanedVariantPolicy(t *testing.T) {
+	policy, err := ParseOrphanedVariantPolicy("adopt")
+	if err != nil || policy != OrphanedVariantPolicyAdopt {
+		t.Errorf("expected the adopt policy but got %v, %v", policy, err)
+	}
+
+	_, err = ParseOrphanedVariantPolicy("ignore")
+	if err == nil {
+		t.Errorf("expected an error for an unknown policy")
+	}
+}
+
+func TestConfigOperator_handleOrphanedVariants_doesNotAdoptDuplicates(t *testing.T) {
+	setup()
+	op.orphanedVariantPolicy = OrphanedVariantPolicyAdopt
+
+	pushClient.On("getVariantsForPlatform", mock.Anything, "android").Return([]Variant{
+		{Name: "myClientId", VariantID: "myAdoptedVariantId"},
+		{Name: "myClientId", VariantID: "myOrphanedVariantId"},
+	}, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(&push.PushVariant{}, nil)
+
+	// the config secret refers to the variant the sync would adopt
+	secrets := newOrphanedVariantSecrets()
+	secrets[0].Data["config"] = []byte("{\"android\":{\"variantId\":\"myAdoptedVariantId\"}}")
+	op.handleOrphanedVariants(context.Background(), pushClient, secrets)
+	op.handleOrphanedVariants(context.Background(), pushClient, secrets)
+
+	kubeHelper.AssertNotCalled(t, "getSecret", mock.Anything, mock.Anything)
+	pushClient.AssertNotCalled(t, "updateAndroidVariant", mock.Anything, mock.Anything)
+}
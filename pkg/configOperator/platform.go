@@ -0,0 +1,143 @@
+package configOperator
+
+import (
+	"context"
+	"strings"
+)
+
+// A variant of any push platform, e.g. *AndroidVariant
+type PlatformVariant interface {
+	// Returns the fields that all variants share. Promoted from the embedded Variant.
+	getVariant() *Variant
+	// Renders the platform section of the client config
+	getJson() ([]byte, error)
+}
+
+func (variant *Variant) getVariant() *Variant {
+	return variant
+}
+
+// A push platform like Android or iOS. To add a new platform implement this interface
+// and register it in NewDefaultPlatformRegistry.
+type Platform interface {
+	// Lowercase name of the platform. Used as key in the client config and in the
+	// `binding/<name>` annotations, and matched case insensitive against the appType of bindings.
+	getName() string
+	// Name of the platform shown in the UI
+	getLabel() string
+	// Builds a variant from the credentials in a binding secret. Name, ID and secret of
+	// the variant are set by the caller.
+	parseBinding(secret *BindingSecret) (PlatformVariant, error)
+	createVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error)
+	// Returns the UPS variant with the given name, nil if there is none
+	findVariant(ctx context.Context, pushClient UpsClient, name string) (*Variant, error)
+	// Returns all variants of the platform in the push application
+	listVariants(ctx context.Context, pushClient UpsClient) ([]Variant, error)
+	// Returns the UPS variant with the given ID, nil if there is none
+	getVariant(ctx context.Context, pushClient UpsClient, variantId string) (*Variant, error)
+	// Replaces the credentials of an existing variant, the variant ID and secret stay the same
+	updateVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error)
+	// The binding the variant was created from is passed since some platforms use several UPS resources
+	deleteVariant(ctx context.Context, pushClient UpsClient, secret *BindingSecret, variantId string) error
+}
+
+// Looks up platforms by name
+type PlatformRegistry struct {
+	platforms map[string]Platform
+	// Registration order, keeps iterations stable
+	names []string
+}
+
+func NewPlatformRegistry(platforms ...Platform) *PlatformRegistry {
+	registry := new(PlatformRegistry)
+
+	registry.platforms = make(map[string]Platform)
+	for _, platform := range platforms {
+		registry.register(platform)
+	}
+
+	return registry
+}
+
+// Returns a registry with all platforms supported by the operator
+func NewDefaultPlatformRegistry() *PlatformRegistry {
+	return NewPlatformRegistry(&AndroidPlatform{}, &IOSPlatform{}, &WebPushPlatform{})
+}
+
+// Adds a platform. A platform with the same name is replaced.
+func (registry *PlatformRegistry) register(platform Platform) {
+	name := platform.getName()
+	if _, ok := registry.platforms[name]; !ok {
+		registry.names = append(registry.names, name)
+	}
+	registry.platforms[name] = platform
+}
+
+// Returns the platform for an appType or platform name, nil if there is none.
+// The lookup is case insensitive, so the appType `IOS` matches the `ios` platform.
+func (registry *PlatformRegistry) get(appType string) Platform {
+	return registry.platforms[strings.ToLower(appType)]
+}
+
+func (registry *PlatformRegistry) all() []Platform {
+	platforms := make([]Platform, 0, len(registry.names))
+	for _, name := range registry.names {
+		platforms = append(platforms, registry.platforms[name])
+	}
+	return platforms
+}
+
+// Returns the UI label of a platform, unknown platforms are shown by name
+func (registry *PlatformRegistry) getLabel(appType string) string {
+	if platform := registry.get(appType); platform != nil {
+		return platform.getLabel()
+	}
+	return appType
+}
+
+// Returns the first variant with the given name from the given UPS resources. Resources that
+// don't exist in older UPS versions are skipped.
+func findVariantByName(ctx context.Context, pushClient UpsClient, name string, resources ...string) (*Variant, error) {
+	variants, err := listVariantsOf(ctx, pushClient, resources...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range variants {
+		if variant.Name == name {
+			return &variant, nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the variant with the given ID from the first of the given UPS resources that has it,
+// nil if none has
+func getVariantOf(ctx context.Context, pushClient UpsClient, variantId string, resources ...string) (*Variant, error) {
+	for _, resource := range resources {
+		variant, err := pushClient.getVariant(ctx, resource, variantId)
+		if IsUpsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return variant, nil
+	}
+	return nil, nil
+}
+
+// Returns the variants of the given UPS resources. Resources that don't exist in older UPS
+// versions are skipped.
+func listVariantsOf(ctx context.Context, pushClient UpsClient, resources ...string) ([]Variant, error) {
+	var result []Variant
+	for _, resource := range resources {
+		variants, err := pushClient.getVariantsForPlatform(ctx, resource)
+		if IsUpsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		result = append(result, variants...)
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlatformRegistry_get(t *testing.T) {
+	registry := NewDefaultPlatformRegistry()
+
+	cases := []struct {
+		appType  string
+		expected string
+		label    string
+	}{
+		{"Android", "android", "Android"},
+		{"IOS", "ios", "iOS"},
+		{"WebPush", "webpush", "Web Push"},
+		{"Cordova", "", "Cordova"},
+	}
+
+	for _, c := range cases {
+		platform := registry.get(c.appType)
+		if c.expected == "" && platform != nil {
+			t.Errorf("expected no platform for `%s` but got `%s`", c.appType, platform.getName())
+		}
+		if c.expected != "" && (platform == nil || platform.getName() != c.expected) {
+			t.Errorf("expected platform `%s` for `%s`", c.expected, c.appType)
+		}
+		if label := registry.getLabel(c.appType); label != c.label {
+			t.Errorf("expected label `%s` for `%s` but got `%s`", c.label, c.appType, label)
+		}
+	}
+}
+
+func TestIOSPlatform_usesTokenVariantsWhenBindingHasPrivateKey(t *testing.T) {
+	platform := &IOSPlatform{}
+	client := new(MockUpsClient)
+	client.On("deleteVariant", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	tokenBinding := &BindingSecret{Data: map[string][]byte{"privateKey": []byte("myPrivateKey")}}
+	certBinding := &BindingSecret{Data: map[string][]byte{"cert": []byte("bXlDZXJ0")}}
+
+	variant, _ := platform.parseBinding(tokenBinding)
+	if _, ok := variant.(*IOSTokenVariant); !ok {
+		t.Errorf("expected a token variant but got %T", variant)
+	}
+
+	variant, _ = platform.parseBinding(certBinding)
+	if _, ok := variant.(*IOSVariant); !ok {
+		t.Errorf("expected a certificate variant but got %T", variant)
+	}
+
+	platform.deleteVariant(context.Background(), client, tokenBinding, "myTokenVariant")
+	platform.deleteVariant(context.Background(), client, certBinding, "myCertVariant")
+
+	client.AssertCalled(t, "deleteVariant", mock.Anything, "ios_token", "myTokenVariant")
+	client.AssertCalled(t, "deleteVariant", mock.Anything, "ios", "myCertVariant")
+}
+
+func TestIOSPlatform_getVariant_looksUpCertificateAndTokenVariants(t *testing.T) {
+	platform := &IOSPlatform{}
+	client := new(MockUpsClient)
+	notFound := &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404}
+	client.On("getVariant", mock.Anything, "ios", mock.Anything).Return(nil, notFound)
+	client.On("getVariant", mock.Anything, "ios_token", "myTokenVariant").Return(&Variant{VariantID: "myTokenVariant"}, nil)
+	client.On("getVariant", mock.Anything, "ios_token", mock.Anything).Return(nil, notFound)
+
+	variant, err := platform.getVariant(context.Background(), client, "myTokenVariant")
+	if err != nil || variant == nil || variant.VariantID != "myTokenVariant" {
+		t.Errorf("expected the token variant but got %v, %v", variant, err)
+	}
+
+	variant, err = platform.getVariant(context.Background(), client, "myDeletedVariant")
+	if err != nil || variant != nil {
+		t.Errorf("expected no variant but got %v, %v", variant, err)
+	}
+}
@@ -0,0 +1,296 @@
+package configOperator
+
+import (
+	"context"
+	"log"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Reasons of the Ready condition of a PushApplication
+const (
+	pushApplicationReasonSynced     = "Synced"
+	pushApplicationReasonSyncFailed = "SyncFailed"
+)
+
+// The application ID and master secret are written to a secret named after the PushApplication
+func pushApplicationSecretName(name string) string {
+	return name + "-push-application"
+}
+
+// Creates the application of a PushApplication in UPS if it doesn't exist yet and keeps its name
+// and description in line with the spec. Returns an error if the PushApplication should be
+// synced again later.
+func (op ConfigOperator) syncPushApplication(ctx context.Context, pushApplication *v1alpha1.PushApplication) error {
+	if pushApplication.DeletionTimestamp != nil {
+		return op.finalizePushApplication(ctx, pushApplication)
+	}
+
+	// Added before the application is created, so that its deletion can't be missed, e.g. while
+	// the operator is down
+	if !hasFinalizer(pushApplication.ObjectMeta, constants.PushApplicationFinalizer) {
+		// The informer cache must not be modified
+		updated := pushApplication.DeepCopy()
+		addFinalizer(&updated.ObjectMeta, constants.PushApplicationFinalizer)
+		updated, err := op.kubeHelper.updatePushApplication(ctx, updated)
+		if err != nil {
+			return errors.Wrapf(err, "cannot add the finalizer to PushApplication `%s`", pushApplication.Name)
+		}
+		pushApplication = updated
+	}
+
+	pushClient := op.pushClientProvider.getAdminClient(ctx)
+	if pushClient == nil {
+		op.setPushApplicationStatus(ctx, pushApplication, nil, false, "", errNoPushClient)
+		return errNoPushClient
+	}
+
+	application, createdByOperator, err := op.savePushApplication(ctx, pushClient, pushApplication)
+	if err != nil {
+		return op.pushApplicationSyncFailed(ctx, pushApplication, nil, false, err)
+	}
+
+	// The ID is recorded in the status even if the secret cannot be written, so that the
+	// application isn't created again
+	secretName, err := op.savePushApplicationSecret(ctx, pushApplication, application)
+	if err != nil {
+		return op.pushApplicationSyncFailed(ctx, pushApplication, application, createdByOperator, err)
+	}
+
+	return op.setPushApplicationStatus(ctx, pushApplication, application, createdByOperator, secretName, nil)
+}
+
+// Returns the application in UPS with the name and description of the spec and whether the
+// operator created it. Applications named by the spec are used as they are found, they are
+// never created.
+func (op ConfigOperator) savePushApplication(ctx context.Context, pushClient UpsClient, pushApplication *v1alpha1.PushApplication) (*PushApplication, bool, error) {
+	desired := &PushApplication{
+		Name:        pushApplication.Spec.Name,
+		Description: pushApplication.Spec.Description,
+	}
+
+	status := pushApplication.Status
+	if applicationId := pushApplication.Spec.ApplicationId; applicationId != "" {
+		existing, err := pushClient.getApplication(ctx, applicationId)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "cannot look up push application `%s`", applicationId)
+		}
+
+		// Only what the spec sets is changed in an application the operator doesn't own
+		if desired.Name == "" {
+			desired.Name = existing.Name
+		}
+		if desired.Description == "" {
+			desired.Description = existing.Description
+		}
+		createdByOperator := status.CreatedByOperator && status.ApplicationId == applicationId
+		application, err := op.updatePushApplicationInUps(ctx, pushClient, pushApplication, existing, desired)
+		return application, createdByOperator, err
+	}
+
+	if desired.Name == "" {
+		desired.Name = pushApplication.Name
+	}
+
+	if applicationId := status.ApplicationId; applicationId != "" {
+		existing, err := pushClient.getApplication(ctx, applicationId)
+		if err == nil {
+			application, err := op.updatePushApplicationInUps(ctx, pushClient, pushApplication, existing, desired)
+			return application, status.CreatedByOperator, err
+		}
+		if !IsUpsNotFound(err) {
+			return nil, false, errors.Wrap(err, "cannot look up the push application")
+		}
+		log.Printf("Push application `%s` of PushApplication `%s` has been deleted in UPS, creating it again", applicationId, pushApplication.Name)
+	}
+
+	created, err := pushClient.createApplication(ctx, desired)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "cannot create the push application")
+	}
+	return created, true, nil
+}
+
+// Brings the name and description of an existing application in line with the spec
+func (op ConfigOperator) updatePushApplicationInUps(ctx context.Context, pushClient UpsClient, pushApplication *v1alpha1.PushApplication, existing *PushApplication, desired *PushApplication) (*PushApplication, error) {
+	if existing.Name == desired.Name && existing.Description == desired.Description {
+		return existing, nil
+	}
+
+	desired.ApplicationId = existing.ApplicationId
+	updated, err := pushClient.updateApplication(ctx, desired)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot update the push application")
+	}
+	log.Printf("Push application `%s` of PushApplication `%s` has been updated", existing.ApplicationId, pushApplication.Name)
+
+	// UPS doesn't return the application on updates
+	if updated.MasterSecret == "" {
+		updated.MasterSecret = existing.MasterSecret
+	}
+	return updated, nil
+}
+
+// Writes the application ID and master secret to a secret that is owned by the PushApplication
+func (op ConfigOperator) savePushApplicationSecret(ctx context.Context, pushApplication *v1alpha1.PushApplication, application *PushApplication) (string, error) {
+	name := pushApplicationSecretName(pushApplication.Name)
+	data := map[string][]byte{
+		constants.PushApplicationSecretApplicationIdKey: []byte(application.ApplicationId),
+		constants.PushApplicationSecretMasterSecretKey:  []byte(application.MasterSecret),
+	}
+
+	secret, err := op.kubeHelper.getSecret(ctx, name)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read the application secret")
+	}
+
+	if secret == nil {
+		_, err = op.kubeHelper.createSecret(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha1.SchemeGroupVersion.String(),
+					Kind:       "PushApplication",
+					Name:       pushApplication.Name,
+					UID:        pushApplication.UID,
+				}},
+			},
+			Data: data,
+		})
+	} else if !reflect.DeepEqual(secret.Data, data) {
+		secret.Data = data
+		_, err = op.kubeHelper.updateSecret(ctx, secret)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "cannot store the application secret")
+	}
+	return name, nil
+}
+
+// Records a failed sync. UPS errors other than an unavailable UPS need a change of the spec,
+// everything else is retried.
+func (op ConfigOperator) pushApplicationSyncFailed(ctx context.Context, pushApplication *v1alpha1.PushApplication, application *PushApplication, createdByOperator bool, err error) error {
+	log.Printf("Cannot sync PushApplication `%s`: %s", pushApplication.Name, err.Error())
+	if ctx.Err() != nil {
+		return err
+	}
+	op.setPushApplicationStatus(ctx, pushApplication, application, createdByOperator, "", err)
+
+	if _, isUpsError := errors.Cause(err).(*UpsError); !isUpsError || IsUpsUnavailable(err) {
+		return err
+	}
+	return nil
+}
+
+// Deletes the application of a PushApplication that is being deleted from UPS and removes the
+// finalizer afterwards. The PushApplication is kept and an error is returned while the
+// application cannot be deleted, so that it isn't left behind in UPS.
+func (op ConfigOperator) finalizePushApplication(ctx context.Context, pushApplication *v1alpha1.PushApplication) error {
+	if !hasFinalizer(pushApplication.ObjectMeta, constants.PushApplicationFinalizer) {
+		return nil
+	}
+
+	err := op.deletePushApplicationFromUps(ctx, pushApplication)
+	if err != nil {
+		return err
+	}
+
+	// The informer cache must not be modified
+	updated := pushApplication.DeepCopy()
+	removeFinalizer(&updated.ObjectMeta, constants.PushApplicationFinalizer)
+	_, err = op.kubeHelper.updatePushApplication(ctx, updated)
+	if err != nil {
+		return errors.Wrapf(err, "cannot remove the finalizer of PushApplication `%s`", pushApplication.Name)
+	}
+
+	log.Printf("The push application of PushApplication `%s` has been deleted, removed the finalizer", pushApplication.Name)
+	return nil
+}
+
+// Applications that are already gone count as deleted. Applications the operator didn't create
+// are left in UPS.
+func (op ConfigOperator) deletePushApplicationFromUps(ctx context.Context, pushApplication *v1alpha1.PushApplication) error {
+	applicationId := pushApplication.Status.ApplicationId
+	if applicationId == "" {
+		log.Printf("PushApplication `%s` has been deleted before its application was created", pushApplication.Name)
+		return nil
+	}
+	if !pushApplication.Status.CreatedByOperator {
+		log.Printf("PushApplication `%s` is being deleted, keeping push application `%s` that hasn't been created by the operator", pushApplication.Name, applicationId)
+		return nil
+	}
+	log.Printf("PushApplication `%s` is being deleted, removing push application `%s`", pushApplication.Name, applicationId)
+
+	pushClient := op.pushClientProvider.getAdminClient(ctx)
+	if pushClient == nil {
+		return errNoPushClient
+	}
+
+	err := pushClient.deleteApplication(ctx, applicationId)
+	if IsUpsNotFound(err) {
+		log.Printf("Push application `%s` has already been deleted", applicationId)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "cannot delete push application `%s`", applicationId)
+	}
+	return nil
+}
+
+// Writes the outcome of a sync into the status. The application ID and its ownership are kept
+// when the sync failed before the application was found or created.
+func (op ConfigOperator) setPushApplicationStatus(ctx context.Context, pushApplication *v1alpha1.PushApplication, application *PushApplication, createdByOperator bool, secretName string, syncErr error) error {
+	updated := pushApplication.DeepCopy()
+
+	if application != nil {
+		updated.Status.ApplicationId = application.ApplicationId
+		updated.Status.CreatedByOperator = createdByOperator
+	}
+
+	condition := v1alpha1.PushApplicationCondition{
+		Type:   v1alpha1.PushApplicationReady,
+		Status: v1.ConditionTrue,
+		Reason: pushApplicationReasonSynced,
+	}
+	if syncErr != nil {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = pushApplicationReasonSyncFailed
+		condition.Message = syncErr.Error()
+		updated.Status.LastError = syncErr.Error()
+	} else {
+		updated.Status.SecretName = secretName
+		updated.Status.LastError = ""
+	}
+	setPushApplicationCondition(&updated.Status, condition)
+
+	if reflect.DeepEqual(updated.Status, pushApplication.Status) {
+		return nil
+	}
+
+	_, err := op.kubeHelper.updatePushApplication(ctx, updated)
+	return errors.Wrapf(err, "cannot update the status of PushApplication `%s`", pushApplication.Name)
+}
+
+// Replaces the condition of the same type. The transition time only changes with the status.
+func setPushApplicationCondition(status *v1alpha1.PushApplicationStatus, condition v1alpha1.PushApplicationCondition) {
+	for i, existing := range status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		condition.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != condition.Status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	status.Conditions = append(status.Conditions, condition)
+}
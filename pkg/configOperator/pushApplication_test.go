@@ -0,0 +1,314 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+func newPushApplication(applicationId string) *push.PushApplication {
+	return &push.PushApplication{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "myPushApplication",
+			Namespace:  "myNamespace",
+			UID:        "myUID",
+			Finalizers: []string{"push.aerogear.org/delete-application"},
+		},
+		Spec:   push.PushApplicationSpec{Name: "myApp", Description: "myDescription"},
+		Status: push.PushApplicationStatus{ApplicationId: applicationId},
+	}
+}
+
+func newDeletingPushApplication(applicationId string) *push.PushApplication {
+	pushApplication := newPushApplication(applicationId)
+	pushApplication.Status.CreatedByOperator = applicationId != ""
+	deletionTimestamp := metav1.Now()
+	pushApplication.DeletionTimestamp = &deletionTimestamp
+	return pushApplication
+}
+
+// Returns the status written by the last update of the PushApplication
+func updatedPushApplicationStatus(t *testing.T) push.PushApplicationStatus {
+	var status *push.PushApplicationStatus
+	for _, call := range kubeHelper.Calls {
+		if call.Method == "updatePushApplication" {
+			status = &call.Arguments.Get(1).(*push.PushApplication).Status
+		}
+	}
+	if status == nil {
+		t.Fatalf("expected the status of the PushApplication to be updated")
+	}
+	return *status
+}
+
+func TestConfigOperator_syncPushApplication_createsTheApplication(t *testing.T) {
+	setup()
+
+	pushClient.On("createApplication", mock.Anything, &PushApplication{Name: "myApp", Description: "myDescription"}).
+		Return(&PushApplication{ApplicationId: "myAppId", Name: "myApp", Description: "myDescription", MasterSecret: "myMasterSecret"}, nil)
+	kubeHelper.On("getSecret", mock.Anything, "myPushApplication-push-application").Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(&v1.Secret{}, nil)
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newPushApplication(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret := kubeHelper.Calls[1].Arguments.Get(1).(*v1.Secret)
+	if string(secret.Data["applicationId"]) != "myAppId" || string(secret.Data["masterSecret"]) != "myMasterSecret" {
+		t.Errorf("unexpected secret data: %v", secret.Data)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].UID != "myUID" {
+		t.Errorf("expected the secret to be owned by the PushApplication but got %+v", secret.OwnerReferences)
+	}
+
+	status := updatedPushApplicationStatus(t)
+	if status.ApplicationId != "myAppId" || !status.CreatedByOperator || status.SecretName != "myPushApplication-push-application" || status.LastError != "" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if len(status.Conditions) != 1 || status.Conditions[0].Status != v1.ConditionTrue {
+		t.Errorf("expected the PushApplication to be ready but got %+v", status.Conditions)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_updatesTheNameAndDescription(t *testing.T) {
+	setup()
+
+	pushClient.On("getApplication", mock.Anything, "myAppId").
+		Return(&PushApplication{ApplicationId: "myAppId", Name: "myApp", Description: "myOldDescription", MasterSecret: "myMasterSecret"}, nil)
+	pushClient.On("updateApplication", mock.Anything, &PushApplication{ApplicationId: "myAppId", Name: "myApp", Description: "myDescription"}).
+		Return(&PushApplication{ApplicationId: "myAppId", Name: "myApp", Description: "myDescription"}, nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "myPushApplication-push-application"},
+		Data: map[string][]byte{
+			"applicationId": []byte("myAppId"),
+			"masterSecret":  []byte("myMasterSecret"),
+		},
+	}
+	kubeHelper.On("getSecret", mock.Anything, "myPushApplication-push-application").Return(secret, nil)
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newPushApplication("myAppId"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertExpectations(t)
+	pushClient.AssertNotCalled(t, "createApplication", mock.Anything, mock.Anything)
+	// the master secret stays the same
+	kubeHelper.AssertNotCalled(t, "updateSecret", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_syncPushApplication_recreatesApplicationsDeletedInUPS(t *testing.T) {
+	setup()
+
+	pushClient.On("getApplication", mock.Anything, "myAppId").Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	pushClient.On("createApplication", mock.Anything, mock.Anything).
+		Return(&PushApplication{ApplicationId: "myNewAppId", Name: "myApp", Description: "myDescription", MasterSecret: "myNewMasterSecret"}, nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "myPushApplication-push-application"},
+		Data: map[string][]byte{
+			"applicationId": []byte("myAppId"),
+			"masterSecret":  []byte("myMasterSecret"),
+		},
+	}
+	kubeHelper.On("getSecret", mock.Anything, "myPushApplication-push-application").Return(secret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(secret, nil)
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newPushApplication("myAppId"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(secret.Data["applicationId"]) != "myNewAppId" || string(secret.Data["masterSecret"]) != "myNewMasterSecret" {
+		t.Errorf("expected the secret to be updated but got %v", secret.Data)
+	}
+	if status := updatedPushApplicationStatus(t); status.ApplicationId != "myNewAppId" {
+		t.Errorf("expected the new application ID in the status but got %+v", status)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_recordsRejectedApplications(t *testing.T) {
+	setup()
+
+	pushClient.On("createApplication", mock.Anything, mock.Anything).Return(nil, &UpsError{Reason: UpsErrorReasonValidationFailed, StatusCode: 400})
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newPushApplication(""))
+	if err != nil {
+		t.Errorf("expected rejected applications not to be retried but got %v", err)
+	}
+
+	status := updatedPushApplicationStatus(t)
+	if status.LastError == "" || len(status.Conditions) != 1 || status.Conditions[0].Status != v1.ConditionFalse {
+		t.Errorf("expected the failure to be recorded but got %+v", status)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_keepsTheApplicationIdIfTheSecretCannotBeWritten(t *testing.T) {
+	setup()
+
+	pushClient.On("createApplication", mock.Anything, mock.Anything).Return(&PushApplication{ApplicationId: "myAppId"}, nil)
+	kubeHelper.On("getSecret", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newPushApplication(""))
+	if err == nil {
+		t.Errorf("expected the sync to be retried")
+	}
+
+	if status := updatedPushApplicationStatus(t); status.ApplicationId != "myAppId" || !status.CreatedByOperator {
+		t.Errorf("expected the application ID to be recorded but got %+v", status)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_addsTheFinalizerBeforeCreatingTheApplication(t *testing.T) {
+	setup()
+
+	pushClient.On("createApplication", mock.Anything, mock.Anything).Return(&PushApplication{ApplicationId: "myAppId"}, nil)
+	kubeHelper.On("getSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(&v1.Secret{}, nil)
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, pushApplication *push.PushApplication) *push.PushApplication {
+			if len(pushClient.Calls) != 0 {
+				t.Errorf("expected the finalizer to be added before the application is created")
+			}
+			return pushApplication
+		},
+		nil,
+	).Once()
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	pushApplication := newPushApplication("")
+	pushApplication.Finalizers = nil
+	err := op.syncPushApplication(context.Background(), pushApplication)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updated := kubeHelper.Calls[0].Arguments.Get(1).(*push.PushApplication)
+	if !hasFinalizer(updated.ObjectMeta, "push.aerogear.org/delete-application") {
+		t.Errorf("expected the finalizer to be added but got %v", updated.Finalizers)
+	}
+	if len(pushApplication.Finalizers) != 0 {
+		t.Errorf("expected the informer cache not to be modified")
+	}
+}
+
+func TestConfigOperator_syncPushApplication_usesTheApplicationOfTheSpec(t *testing.T) {
+	setup()
+
+	pushClient.On("getApplication", mock.Anything, "myExistingAppId").
+		Return(&PushApplication{ApplicationId: "myExistingAppId", Name: "myExistingApp", Description: "myExistingDescription", MasterSecret: "myMasterSecret"}, nil)
+	kubeHelper.On("getSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(&v1.Secret{}, nil)
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	pushApplication := newPushApplication("")
+	pushApplication.Spec = push.PushApplicationSpec{ApplicationId: "myExistingAppId"}
+	err := op.syncPushApplication(context.Background(), pushApplication)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertNotCalled(t, "createApplication", mock.Anything, mock.Anything)
+	// the name and description aren't set by the spec
+	pushClient.AssertNotCalled(t, "updateApplication", mock.Anything, mock.Anything)
+	status := updatedPushApplicationStatus(t)
+	if status.ApplicationId != "myExistingAppId" || status.CreatedByOperator {
+		t.Errorf("expected the application to be used without being owned but got %+v", status)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_doesNotCreateMissingApplicationsOfTheSpec(t *testing.T) {
+	setup()
+
+	pushClient.On("getApplication", mock.Anything, "myExistingAppId").Return(nil, &UpsError{Reason: UpsErrorReasonNotFound, StatusCode: 404})
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	pushApplication := newPushApplication("")
+	pushApplication.Spec.ApplicationId = "myExistingAppId"
+	err := op.syncPushApplication(context.Background(), pushApplication)
+	if err != nil {
+		t.Errorf("expected the missing application not to be retried but got %v", err)
+	}
+
+	pushClient.AssertNotCalled(t, "createApplication", mock.Anything, mock.Anything)
+	if status := updatedPushApplicationStatus(t); status.LastError == "" || status.ApplicationId != "" {
+		t.Errorf("expected the failure to be recorded but got %+v", status)
+	}
+}
+
+func TestConfigOperator_syncPushApplication_deletesTheApplicationAndRemovesTheFinalizer(t *testing.T) {
+	setup()
+
+	pushClient.On("deleteApplication", mock.Anything, "myAppId").Return(nil).Once()
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	pushApplication := newDeletingPushApplication("myAppId")
+	err := op.syncPushApplication(context.Background(), pushApplication)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pushClient.AssertExpectations(t)
+
+	updated := kubeHelper.Calls[0].Arguments.Get(1).(*push.PushApplication)
+	if hasFinalizer(updated.ObjectMeta, "push.aerogear.org/delete-application") {
+		t.Errorf("expected the finalizer to be removed but got %v", updated.Finalizers)
+	}
+	if !hasFinalizer(pushApplication.ObjectMeta, "push.aerogear.org/delete-application") {
+		t.Errorf("expected the informer cache not to be modified")
+	}
+}
+
+func TestConfigOperator_syncPushApplication_keepsTheFinalizerWhileUPSIsUnavailable(t *testing.T) {
+	setup()
+
+	pushClient.On("deleteApplication", mock.Anything, "myAppId").Return(errUpsCircuitOpen)
+
+	err := op.syncPushApplication(context.Background(), newDeletingPushApplication("myAppId"))
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected the deletion to be retried but got %v", err)
+	}
+	kubeHelper.AssertNotCalled(t, "updatePushApplication", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_syncPushApplication_removesTheFinalizerOfApplicationsThatWereNeverCreated(t *testing.T) {
+	setup()
+
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.syncPushApplication(context.Background(), newDeletingPushApplication(""))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	pushClient.AssertNotCalled(t, "deleteApplication", mock.Anything, mock.Anything)
+	kubeHelper.AssertCalled(t, "updatePushApplication", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_syncPushApplication_keepsApplicationsThatWereNotCreatedByTheOperator(t *testing.T) {
+	setup()
+
+	kubeHelper.On("updatePushApplication", mock.Anything, mock.Anything).Return(nil, nil)
+
+	pushApplication := newDeletingPushApplication("myAppId")
+	pushApplication.Status.CreatedByOperator = false
+	err := op.syncPushApplication(context.Background(), pushApplication)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pushClient.AssertNotCalled(t, "deleteApplication", mock.Anything, mock.Anything)
+	updated := kubeHelper.Calls[0].Arguments.Get(1).(*push.PushApplication)
+	if hasFinalizer(updated.ObjectMeta, "push.aerogear.org/delete-application") {
+		t.Errorf("expected the finalizer to be removed but got %v", updated.Finalizers)
+	}
+}
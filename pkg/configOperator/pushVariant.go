@@ -0,0 +1,322 @@
+package configOperator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Reasons of the Ready condition of a PushVariant
+const (
+	pushVariantReasonSynced     = "Synced"
+	pushVariantReasonSyncFailed = "SyncFailed"
+)
+
+// The PushVariant of a binding is named after the client and the platform, so that a newer
+// binding of the same client replaces it
+func pushVariantName(clientId string, platform string) string {
+	return fmt.Sprintf("%s-%s", clientId, platform)
+}
+
+// Creates or updates the PushVariant of a binding secret. The credentials are copied into a
+// secret that is owned by the PushVariant.
+func (op ConfigOperator) savePushVariant(ctx context.Context, platform Platform, secret *BindingSecret) error {
+	spec := pushVariantSpecOfBinding(platform, secret)
+	clientId := spec.ClientId
+	name := pushVariantName(clientId, platform.getName())
+
+	// Written first, so that the PushVariant can be synced as soon as it exists
+	credentials, err := op.saveCredentials(ctx, platform, name, spec.SecretRef.Name, secret.Data)
+	if err != nil {
+		return errors.Wrap(err, "cannot store the credentials")
+	}
+
+	pushVariant, err := op.kubeHelper.getPushVariant(ctx, name)
+	if err != nil {
+		return errors.Wrap(err, "cannot look up the PushVariant")
+	}
+	if pushVariant == nil {
+		pushVariant = &v1alpha1.PushVariant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"clientId": clientId},
+			},
+			Spec: spec,
+		}
+		op.setPushVariantOwner(ctx, pushVariant, clientId)
+		pushVariant, err = op.kubeHelper.createPushVariant(ctx, pushVariant)
+	} else {
+		pushVariant.Spec = spec
+		op.setPushVariantOwner(ctx, pushVariant, clientId)
+		pushVariant, err = op.kubeHelper.updatePushVariant(ctx, pushVariant)
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot store the PushVariant")
+	}
+	log.Printf("PushVariant `%s` for the %s binding of client `%s` has been stored", name, platform.getName(), clientId)
+
+	// The credentials are garbage collected together with the PushVariant
+	if len(credentials.OwnerReferences) == 0 {
+		credentials.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "PushVariant",
+			Name:       pushVariant.Name,
+			UID:        pushVariant.UID,
+		}}
+		if _, err = op.kubeHelper.updateSecret(ctx, credentials); err != nil {
+			return errors.Wrap(err, "cannot set the owner of the credentials")
+		}
+	}
+
+	// Nothing has been stored in dry-run mode, so no event would sync the PushVariant
+	if isDryRun(op.kubeHelper) {
+		return op.syncPushVariantWithCredentials(ctx, platform, pushVariant, credentials)
+	}
+	return nil
+}
+
+// Makes the mobile client the owner of a PushVariant, so that the PushVariant and with it the
+// variant are deleted together with the client. PushVariants stored by older versions of the
+// operator get the owner when their binding is stored again.
+func (op ConfigOperator) setPushVariantOwner(ctx context.Context, pushVariant *v1alpha1.PushVariant, clientId string) {
+	if len(pushVariant.OwnerReferences) > 0 {
+		return
+	}
+
+	mobileClient, err := op.kubeHelper.getMobileClient(ctx, clientId)
+	if err != nil {
+		log.Printf("Cannot look up mobile client `%s`, storing PushVariant `%s` without an owner: %s", clientId, pushVariant.Name, err.Error())
+		return
+	}
+	if mobileClient == nil {
+		log.Printf("Mobile client `%s` does not exist, storing PushVariant `%s` without an owner", clientId, pushVariant.Name)
+		return
+	}
+
+	pushVariant.OwnerReferences = []metav1.OwnerReference{mobileClientOwnerReference(mobileClient)}
+}
+
+func pushVariantSpecOfBinding(platform Platform, secret *BindingSecret) v1alpha1.PushVariantSpec {
+	clientId := string(secret.Data[constants.BindingDataClientIdKey])
+	production, _ := strconv.ParseBool(string(secret.Data[constants.BindingDataIOSIsProductionKey]))
+
+	return v1alpha1.PushVariantSpec{
+		Platform:            platform.getName(),
+		ClientId:            clientId,
+		SecretRef:           v1.LocalObjectReference{Name: pushVariantName(clientId, platform.getName()) + "-credentials"},
+		Production:          production,
+		ServiceBindingId:    string(secret.Data[constants.BindingDataServiceBindingIdKey]),
+		ServiceInstanceName: string(secret.Data[constants.BindingDataServiceInstanceNameKey]),
+	}
+}
+
+// The credentials secret is labelled with the PushVariant, changes of the credentials sync it
+func (op ConfigOperator) saveCredentials(ctx context.Context, platform Platform, pushVariantName string, name string, data map[string][]byte) (*v1.Secret, error) {
+	credentials, err := op.kubeHelper.getSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := platform.(*WebPushPlatform); ok {
+		data, err = withVapidKeys(data, credentials)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if credentials == nil {
+		return op.kubeHelper.createSecret(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{constants.PushVariantLabelKey: pushVariantName},
+			},
+			Data: data,
+		})
+	}
+
+	if credentials.Labels == nil {
+		credentials.Labels = map[string]string{}
+	}
+	credentials.Labels[constants.PushVariantLabelKey] = pushVariantName
+	credentials.Data = data
+	return op.kubeHelper.updateSecret(ctx, credentials)
+}
+
+// Creates or updates the variant of a PushVariant in UPS and records the outcome in its status.
+// Returns an error if the PushVariant should be synced again later.
+func (op ConfigOperator) syncPushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) error {
+	spec := pushVariant.Spec
+
+	platform := op.platforms.get(spec.Platform)
+	if platform == nil {
+		return op.setPushVariantStatus(ctx, pushVariant, "", "", errors.Errorf("unsupported platform `%s`", spec.Platform))
+	}
+
+	credentials, err := op.kubeHelper.getSecret(ctx, spec.SecretRef.Name)
+	if err != nil {
+		return errors.Wrap(err, "cannot read the credentials")
+	}
+	if credentials == nil {
+		// Retried with a backoff until the secret is created
+		err = errors.Errorf("the credentials secret `%s` does not exist", spec.SecretRef.Name)
+		op.setPushVariantStatus(ctx, pushVariant, "", "", err)
+		return err
+	}
+
+	return op.syncPushVariantWithCredentials(ctx, platform, pushVariant, credentials)
+}
+
+// Syncs a PushVariant with credentials that have already been read
+func (op ConfigOperator) syncPushVariantWithCredentials(ctx context.Context, platform Platform, pushVariant *v1alpha1.PushVariant, credentials *v1.Secret) error {
+	spec := pushVariant.Spec
+
+	variantId, configSecretName, err := op.handleVariant(ctx, platform, bindingSecretOfPushVariant(pushVariant, credentials))
+	if err == nil {
+		pushVariantSyncs.WithLabelValues(platform.getName(), bindingOutcomeSuccess).Inc()
+		return op.setPushVariantStatus(ctx, pushVariant, variantId, configSecretName, nil)
+	}
+
+	op.logVariantCreationError(platform.getName(), spec.ClientId, err)
+	if ctx.Err() != nil {
+		pushVariantSyncs.WithLabelValues(platform.getName(), bindingOutcomeRetry).Inc()
+		return err
+	}
+	op.recordEvent(ctx, spec.ClientId, spec.ServiceBindingId, v1.EventTypeWarning, eventReasonVariantCreationFailed,
+		fmt.Sprintf("Cannot create or update the %s variant: %s", platform.getName(), err.Error()))
+	op.setPushVariantStatus(ctx, pushVariant, "", "", err)
+
+	// Rejected variants need a change of the PushVariant or its credentials, everything else,
+	// e.g. an unavailable UPS or a conflicting update of the config secret, is retried
+	if IsUpsPermanent(err) {
+		pushVariantSyncs.WithLabelValues(platform.getName(), bindingOutcomeFailed).Inc()
+		return nil
+	}
+	pushVariantSyncs.WithLabelValues(platform.getName(), bindingOutcomeRetry).Inc()
+	return err
+}
+
+// Removes the variant of a deleted PushVariant from UPS and from the config secret
+func (op ConfigOperator) handleDeletedPushVariant(ctx context.Context, pushVariant *v1alpha1.PushVariant) {
+	log.Printf("PushVariant `%s` has been deleted, removing the %s variant of client `%s`", pushVariant.Name, pushVariant.Spec.Platform, pushVariant.Spec.ClientId)
+
+	// Stand in for the binding secret, the credentials might already be gone
+	op.handleDeleteVariant(ctx, &BindingSecret{
+		Data: map[string][]byte{
+			constants.BindingDataAppTypeKey:          []byte(pushVariant.Spec.Platform),
+			constants.BindingDataClientIdKey:         []byte(pushVariant.Spec.ClientId),
+			constants.BindingDataServiceBindingIdKey: []byte(pushVariant.Spec.ServiceBindingId),
+		},
+	})
+}
+
+// Deletes the PushVariant of a deleted binding, which removes the variant once the deletion is
+// handled. Variants of bindings that have not been stored as PushVariants are removed right away.
+func (op ConfigOperator) deleteVariantOfBinding(ctx context.Context, secret *BindingSecret) {
+	platform := op.platforms.get(string(secret.Data[constants.BindingDataAppTypeKey]))
+	if platform == nil {
+		return
+	}
+
+	clientId := string(secret.Data[constants.BindingDataClientIdKey])
+	name := pushVariantName(clientId, platform.getName())
+	pushVariant, err := op.kubeHelper.getPushVariant(ctx, name)
+	if err != nil {
+		log.Printf("Cannot look up PushVariant `%s`: %s", name, err.Error())
+		return
+	}
+
+	if pushVariant == nil {
+		op.handleDeleteVariant(ctx, secret)
+		return
+	}
+
+	// The PushVariant has been taken over by a newer binding
+	bindingId := string(secret.Data[constants.BindingDataServiceBindingIdKey])
+	if bindingId != "" && pushVariant.Spec.ServiceBindingId != bindingId {
+		log.Printf("PushVariant `%s` belongs to binding %s, keeping it", name, pushVariant.Spec.ServiceBindingId)
+		return
+	}
+
+	err = op.kubeHelper.deletePushVariant(ctx, name)
+	if err != nil {
+		log.Printf("Cannot delete PushVariant `%s`: %s", name, err.Error())
+		return
+	}
+	log.Printf("PushVariant `%s` has been deleted", name)
+}
+
+// The platforms read the credentials from binding secrets, so the PushVariant is turned into one
+func bindingSecretOfPushVariant(pushVariant *v1alpha1.PushVariant, credentials *v1.Secret) *BindingSecret {
+	data := make(map[string][]byte)
+	for key, value := range credentials.Data {
+		data[key] = value
+	}
+
+	data[constants.BindingDataAppTypeKey] = []byte(pushVariant.Spec.Platform)
+	data[constants.BindingDataClientIdKey] = []byte(pushVariant.Spec.ClientId)
+	data[constants.BindingDataServiceBindingIdKey] = []byte(pushVariant.Spec.ServiceBindingId)
+	data[constants.BindingDataServiceInstanceNameKey] = []byte(pushVariant.Spec.ServiceInstanceName)
+	data[constants.BindingDataIOSIsProductionKey] = []byte(strconv.FormatBool(pushVariant.Spec.Production))
+
+	return &BindingSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: credentials.Name},
+		Data:       data,
+	}
+}
+
+// Writes the outcome of a sync into the status. The variant ID and config secret are kept when
+// the sync failed, the variant might still exist.
+func (op ConfigOperator) setPushVariantStatus(ctx context.Context, pushVariant *v1alpha1.PushVariant, variantId string, configSecretName string, syncErr error) error {
+	updated := pushVariant.DeepCopy()
+
+	condition := v1alpha1.PushVariantCondition{
+		Type:   v1alpha1.PushVariantReady,
+		Status: v1.ConditionTrue,
+		Reason: pushVariantReasonSynced,
+	}
+	if syncErr != nil {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = pushVariantReasonSyncFailed
+		condition.Message = syncErr.Error()
+		updated.Status.LastError = syncErr.Error()
+	} else {
+		updated.Status.VariantId = variantId
+		updated.Status.ConfigSecretName = configSecretName
+		updated.Status.LastError = ""
+	}
+	setPushVariantCondition(&updated.Status, condition)
+
+	if reflect.DeepEqual(updated.Status, pushVariant.Status) {
+		return nil
+	}
+
+	_, err := op.kubeHelper.updatePushVariant(ctx, updated)
+	return errors.Wrapf(err, "cannot update the status of PushVariant `%s`", pushVariant.Name)
+}
+
+// Replaces the condition of the same type. The transition time only changes with the status.
+func setPushVariantCondition(status *v1alpha1.PushVariantStatus, condition v1alpha1.PushVariantCondition) {
+	for i, existing := range status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		condition.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != condition.Status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	status.Conditions = append(status.Conditions, condition)
+}
@@ -0,0 +1,305 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+)
+
+func newDeletedBindingSecret(bindingId string) *BindingSecret {
+	return &BindingSecret{
+		Data: map[string][]byte{
+			"appType":          []byte("android"),
+			"clientId":         []byte("myClientId"),
+			"serviceBindingId": []byte(bindingId),
+		},
+	}
+}
+
+func TestConfigOperator_deleteVariantOfBinding_deletesThePushVariant(t *testing.T) {
+	setup()
+
+	pushVariant := &push.PushVariant{Spec: push.PushVariantSpec{ServiceBindingId: "myServiceBindingId"}}
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(pushVariant, nil)
+	kubeHelper.On("deletePushVariant", mock.Anything, "myClientId-android").Return(nil).Once()
+
+	op.deleteVariantOfBinding(context.Background(), newDeletedBindingSecret("myServiceBindingId"))
+
+	// the variant is removed once the deletion of the PushVariant is handled
+	kubeHelper.AssertExpectations(t)
+	kubeHelper.AssertNotCalled(t, "findMobileClientConfig", mock.Anything, mock.Anything)
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_deleteVariantOfBinding_keepsThePushVariantOfANewerBinding(t *testing.T) {
+	setup()
+
+	pushVariant := &push.PushVariant{Spec: push.PushVariantSpec{ServiceBindingId: "myNewServiceBindingId"}}
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(pushVariant, nil)
+
+	op.deleteVariantOfBinding(context.Background(), newDeletedBindingSecret("myOldServiceBindingId"))
+
+	kubeHelper.AssertNotCalled(t, "deletePushVariant", mock.Anything, mock.Anything)
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSecretController_removesVariantsOfDeletedPushVariants(t *testing.T) {
+	setup()
+
+	pushVariant := push.PushVariant{
+		ObjectMeta: metav1.ObjectMeta{Name: "myClientId-android", Namespace: "myNamespace"},
+		Spec: push.PushVariantSpec{
+			Platform:         "android",
+			ClientId:         "myClientId",
+			SecretRef:        v1.LocalObjectReference{Name: "myCredentials"},
+			ServiceBindingId: "myServiceBindingId",
+		},
+	}
+	configSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "mySecretName",
+			Annotations: map[string]string{
+				"binding/android": "myServiceBindingId",
+			},
+		},
+		Data: map[string][]byte{
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+			"config":              []byte("{\"android\":{\"variantId\":\"myVariantId\"}}"),
+		},
+	}
+	pushVariantWatcher := watch.NewFake()
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{Items: []push.PushVariant{pushVariant}}, &push.PushVariant{}, pushVariantWatcher))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	// the credentials are gone before the PushVariant is deleted
+	kubeHelper.On("getSecret", mock.Anything, "myCredentials").Return(nil, nil)
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(configSecret, nil)
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName")
+	annotationHelper.On("removeAnnotationFromMobileClient", mock.Anything, "myClientId", "android", "myServiceInstanceName")
+
+	calls := make(chan string, 10)
+	pushClient.On("deleteVariant", mock.Anything, "android", "myVariantId").Return(nil).Run(func(args mock.Arguments) {
+		calls <- "deleteVariant"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSecretController(*op).run(ctx, ctx.Done())
+
+	pushVariantWatcher.Delete(&pushVariant)
+
+	waitFor(t, calls, "deleteVariant")
+	cancel()
+
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "mySecretName")
+}
+
+func TestSecretController_ignoresStatusUpdatesOfPushVariants(t *testing.T) {
+	setup()
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	controller := newSecretController(*op)
+
+	old := &push.PushVariant{
+		ObjectMeta: metav1.ObjectMeta{Name: "myClientId-android", Namespace: "myNamespace", ResourceVersion: "1"},
+		Spec:       push.PushVariantSpec{Platform: "android", ClientId: "myClientId"},
+	}
+	synced := old.DeepCopy()
+	synced.ResourceVersion = "2"
+	synced.Status.VariantId = "myVariantId"
+
+	controller.enqueueUpdatedPushVariant(old, synced)
+	if controller.queue.Len() != 0 {
+		t.Errorf("expected a status update to be ignored but %d items are queued", controller.queue.Len())
+	}
+
+	changed := synced.DeepCopy()
+	changed.ResourceVersion = "3"
+	changed.Spec.Production = true
+
+	controller.enqueueUpdatedPushVariant(synced, changed)
+	if controller.queue.Len() != 1 {
+		t.Errorf("expected a change of the spec to be queued but %d items are queued", controller.queue.Len())
+	}
+}
+
+func TestSecretController_syncsPushVariantsWhenTheirCredentialsChange(t *testing.T) {
+	setup()
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	controller := newSecretController(*op)
+
+	old := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "myClientId-android-credentials",
+			Namespace:       "myNamespace",
+			ResourceVersion: "1",
+			Labels:          map[string]string{"push.aerogear.org/push-variant": "myClientId-android"},
+		},
+		Data: map[string][]byte{"googleKey": []byte("myGoogleKey")},
+	}
+	resynced := old.DeepCopy()
+	resynced.ResourceVersion = "2"
+
+	controller.enqueueUpdatedCredentials(old, resynced)
+	if controller.queue.Len() != 0 {
+		t.Errorf("expected unchanged credentials to be ignored but %d items are queued", controller.queue.Len())
+	}
+
+	rotated := resynced.DeepCopy()
+	rotated.ResourceVersion = "3"
+	rotated.Data["googleKey"] = []byte("myNewGoogleKey")
+
+	controller.enqueueUpdatedCredentials(resynced, rotated)
+	if controller.queue.Len() != 1 {
+		t.Fatalf("expected the PushVariant to be queued but %d items are queued", controller.queue.Len())
+	}
+	item, _ := controller.queue.Get()
+	if item.(queueItem).kind != pushVariantChanged || item.(queueItem).key != "myNamespace/myClientId-android" {
+		t.Errorf("unexpected queue item %+v", item)
+	}
+}
+
+func TestSetPushVariantCondition_keepsTheTransitionTimeWhileTheStatusIsUnchanged(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	status := &push.PushVariantStatus{
+		Conditions: []push.PushVariantCondition{
+			{Type: push.PushVariantReady, Status: v1.ConditionFalse, LastTransitionTime: transitionTime, Message: "first error"},
+		},
+	}
+
+	setPushVariantCondition(status, push.PushVariantCondition{Type: push.PushVariantReady, Status: v1.ConditionFalse, Message: "second error"})
+	if len(status.Conditions) != 1 || !status.Conditions[0].LastTransitionTime.Equal(&transitionTime) || status.Conditions[0].Message != "second error" {
+		t.Errorf("expected the message to be replaced and the transition time to be kept but got %+v", status.Conditions)
+	}
+
+	setPushVariantCondition(status, push.PushVariantCondition{Type: push.PushVariantReady, Status: v1.ConditionTrue})
+	if status.Conditions[0].LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("expected the transition time to change with the status")
+	}
+}
+
+func newWebPushBindingSecret() *BindingSecret {
+	return &BindingSecret{
+		Data: map[string][]byte{
+			"appType":          []byte("webpush"),
+			"clientId":         []byte("myClientId"),
+			"vapidSubject":     []byte("mailto:admin@example.org"),
+			"serviceBindingId": []byte("myServiceBindingId"),
+		},
+	}
+}
+
+func TestConfigOperator_savePushVariant_generatesVapidKeysOnce(t *testing.T) {
+	setup()
+
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-webpush-credentials").Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(func(ctx context.Context, secret *v1.Secret) *v1.Secret {
+		return secret
+	}, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-webpush").Return(nil, nil)
+	kubeHelper.On("createPushVariant", mock.Anything, mock.Anything).Return(&push.PushVariant{}, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	err := op.savePushVariant(context.Background(), op.platforms.get("webpush"), newWebPushBindingSecret())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "createSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return validateVapidKeys(string(secret.Data["vapidPublicKey"]), string(secret.Data["vapidPrivateKey"])) == nil &&
+			string(secret.Data["vapidSubject"]) == "mailto:admin@example.org"
+	}))
+}
+
+func TestConfigOperator_savePushVariant_keepsStoredVapidKeys(t *testing.T) {
+	setup()
+	publicKey, privateKey, _ := generateVapidKeys()
+
+	credentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "myClientId-webpush-credentials",
+			OwnerReferences: []metav1.OwnerReference{{Name: "myClientId-webpush"}},
+		},
+		Data: map[string][]byte{
+			"vapidPublicKey":  []byte(publicKey),
+			"vapidPrivateKey": []byte(privateKey),
+		},
+	}
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-webpush-credentials").Return(credentials, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(func(ctx context.Context, secret *v1.Secret) *v1.Secret {
+		return secret
+	}, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-webpush").Return(&push.PushVariant{}, nil)
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(&push.PushVariant{}, nil)
+
+	err := op.savePushVariant(context.Background(), op.platforms.get("webpush"), newWebPushBindingSecret())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return string(secret.Data["vapidPublicKey"]) == publicKey && string(secret.Data["vapidPrivateKey"]) == privateKey
+	}))
+}
+
+func TestConfigOperator_savePushVariant_isOwnedByTheMobileClient(t *testing.T) {
+	setup()
+
+	mobileClient := &mobile.MobileClient{ObjectMeta: metav1.ObjectMeta{Name: "myClientId", UID: "myUID"}}
+	kubeHelper = new(MockKubeHelper)
+	kubeHelper.On("getMobileClient", mock.Anything, "myClientId").Return(mobileClient, nil)
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-android-credentials").Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(&v1.Secret{}, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("createPushVariant", mock.Anything, mock.Anything).Return(&push.PushVariant{}, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	op.kubeHelper = kubeHelper
+
+	bindingSecret := &BindingSecret{
+		Data: map[string][]byte{
+			"appType":          []byte("android"),
+			"clientId":         []byte("myClientId"),
+			"googleKey":        []byte("myGoogleKey"),
+			"projectNumber":    []byte("myProjectNumber"),
+			"serviceBindingId": []byte("myServiceBindingId"),
+		},
+	}
+	err := op.savePushVariant(context.Background(), op.platforms.get("android"), bindingSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeHelper.AssertCalled(t, "createPushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return len(pushVariant.OwnerReferences) == 1 &&
+			pushVariant.OwnerReferences[0].Kind == "MobileClient" &&
+			pushVariant.OwnerReferences[0].UID == "myUID"
+	}))
+}
@@ -0,0 +1,108 @@
+package configOperator
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"k8s.io/api/core/v1"
+)
+
+// Brings PushVariants, config secrets and UPS in line with the current bindings. Runs at startup
+// so that changes made while the operator was not running are not missed. All steps are
+// idempotent: existing PushVariants are updated instead of created again, and the controller
+// syncs every PushVariant once its informer has listed them. Stops between two items once stop
+// is closed.
+func (op ConfigOperator) reconcile(ctx context.Context, stop <-chan struct{}) {
+	log.Print("Reconciling bindings, config secrets and UPS variants")
+
+	op.reconcilePendingBindings(ctx, stop)
+	op.reconcileDeletedBindings(ctx, stop)
+	if isStopped(stop) {
+		return
+	}
+
+	// Variants that have been deleted in UPS leave orphaned config secrets behind
+	op.compareUPSVariantsWithClientConfigs(ctx)
+
+	log.Print("Reconcile finished")
+}
+
+// Binding secrets are deleted once they are handled, so every remaining one still needs a PushVariant
+func (op ConfigOperator) reconcilePendingBindings(ctx context.Context, stop <-chan struct{}) {
+	secrets, err := op.kubeHelper.listSecrets(ctx, constants.BindingSecretSelector)
+	if err != nil {
+		log.Printf("Cannot list binding secrets: %s", err.Error())
+		return
+	}
+
+	for i := range secrets.Items {
+		if isStopped(stop) || ctx.Err() != nil {
+			return
+		}
+		log.Printf("Handling pending binding secret `%s`", secrets.Items[i].Name)
+		op.handleAddSecret(ctx, &secrets.Items[i])
+	}
+}
+
+// Removes the variants of service bindings that no longer exist from the config secrets and UPS
+func (op ConfigOperator) reconcileDeletedBindings(ctx context.Context, stop <-chan struct{}) {
+	pushClient := op.pushClientProvider.getPushClient(ctx)
+	if pushClient == nil {
+		log.Print("Cannot look for deleted bindings since the push client cannot be built")
+		return
+	}
+
+	selector := fmt.Sprintf("serviceName=ups,pushApplicationId=%s", pushClient.getApplicationId())
+	configSecrets, err := op.kubeHelper.listSecrets(ctx, selector)
+	if err != nil {
+		log.Printf("Cannot list config secrets: %s", err.Error())
+		return
+	}
+
+	bindings, err := op.kubeHelper.listServiceBindings(ctx)
+	if err != nil {
+		log.Printf("Cannot list service bindings: %s", err.Error())
+		return
+	}
+
+	existingBindings := make(map[string]bool)
+	for _, binding := range bindings.Items {
+		existingBindings[binding.Spec.ExternalID] = true
+	}
+
+	for i := range configSecrets.Items {
+		if isStopped(stop) || ctx.Err() != nil {
+			return
+		}
+		op.removeVariantsOfDeletedBindings(ctx, &configSecrets.Items[i], func(bindingId string) bool {
+			return !existingBindings[bindingId]
+		})
+	}
+}
+
+// Removes the variants of a config secret whose service binding has been deleted from the config
+// secret and from UPS
+func (op ConfigOperator) removeVariantsOfDeletedBindings(ctx context.Context, configSecret *v1.Secret, isDeleted func(bindingId string) bool) {
+	clientId := configSecret.Labels["clientId"]
+
+	for _, platform := range op.platforms.all() {
+		bindingId := configSecret.Annotations[fmt.Sprintf("binding/%s", platform.getName())]
+		if bindingId == "" || !isDeleted(bindingId) {
+			continue
+		}
+
+		log.Printf("Binding %s of client %s has been deleted, removing the %s variant", bindingId, clientId, platform.getName())
+
+		// Stand in for the deleted binding secret
+		deletedSecret := &BindingSecret{
+			Data: map[string][]byte{
+				constants.BindingDataAppTypeKey:          []byte(platform.getName()),
+				constants.BindingDataClientIdKey:         []byte(clientId),
+				constants.BindingDataServiceBindingIdKey: []byte(bindingId),
+			},
+		}
+		op.deleteVariantOfBinding(ctx, deletedSecret)
+	}
+}
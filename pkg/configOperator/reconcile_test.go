@@ -0,0 +1,166 @@
+package configOperator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigOperator_reconcilePendingBindings_storesPushVariants(t *testing.T) {
+	setup()
+
+	bindingSecret := v1.Secret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	kubeHelper.On("listSecrets", mock.Anything, "secretType=mobile-client-binding-secret").Return(&v1.SecretList{Items: []v1.Secret{bindingSecret}}, nil)
+	kubeHelper.On("getSecret", mock.Anything, "myClientId-android-credentials").Return(nil, nil)
+	kubeHelper.On("createSecret", mock.Anything, mock.Anything).Return(func(ctx context.Context, secret *v1.Secret) *v1.Secret {
+		return secret
+	}, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("createPushVariant", mock.Anything, mock.Anything).Return(func(ctx context.Context, pushVariant *push.PushVariant) *push.PushVariant {
+		pushVariant.UID = "myPushVariantUID"
+		return pushVariant
+	}, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("deleteSecret", mock.Anything, "myBindingSecret").Once()
+
+	op.reconcilePendingBindings(context.Background(), nil)
+
+	// the binding is stored, the variant is created when the PushVariant is synced
+	kubeHelper.AssertCalled(t, "createSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return secret.Name == "myClientId-android-credentials" &&
+			reflect.DeepEqual(secret.Labels, map[string]string{"push.aerogear.org/push-variant": "myClientId-android"}) &&
+			string(secret.Data["googleKey"]) == "myGoogleKey"
+	}))
+	kubeHelper.AssertCalled(t, "createPushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return pushVariant.Name == "myClientId-android" && reflect.DeepEqual(pushVariant.Spec, push.PushVariantSpec{
+			Platform:            "android",
+			ClientId:            "myClientId",
+			SecretRef:           v1.LocalObjectReference{Name: "myClientId-android-credentials"},
+			ServiceBindingId:    "myServiceBindingId",
+			ServiceInstanceName: "myServiceInstanceName",
+		})
+	}))
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return len(secret.OwnerReferences) == 1 && secret.OwnerReferences[0].UID == "myPushVariantUID"
+	}))
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "myBindingSecret")
+	pushClient.AssertNotCalled(t, "createAndroidVariant", mock.Anything, mock.Anything)
+}
+
+func TestConfigOperator_syncPushVariant_adoptsExistingVariant(t *testing.T) {
+	setup()
+
+	bindingSecret := v1.Secret{
+		Data: map[string][]byte{
+			"appType":             []byte("Android"),
+			"clientId":            []byte("myClientId"),
+			"googleKey":           []byte("myGoogleKey"),
+			"projectNumber":       []byte("myProjectNumber"),
+			"serviceBindingId":    []byte("myServiceBindingId"),
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+		},
+	}
+	bindingSecret.Labels = map[string]string{
+		"secretType": "mobile-client-binding-secret",
+	}
+	bindingSecret.Name = "myBindingSecret"
+
+	configSecret := &v1.Secret{
+		Data: map[string][]byte{
+			"config": []byte("{}"),
+		},
+	}
+	configSecret.Name = "mySecretName"
+
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	kubeHelper.On("createClientConfigSecret", mock.Anything, "myClientId", "myServiceInstanceName", "myPushServiceInstanceId", "myPushApplicationId").Return(configSecret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+
+	// the variant was created before the operator was stopped but the config secret was never written
+	pushClient.On("getVariantsForPlatform", mock.Anything, "android").Return([]Variant{
+		{Name: "someOtherClient", VariantID: "someOtherVariantId"},
+		{Name: "myClientId", VariantID: "myVariantId", Secret: "myVariantSecret"},
+	}, nil)
+	pushClient.On("updateAndroidVariant", mock.Anything, mock.Anything).Return(func(ctx context.Context, variant *AndroidVariant) *AndroidVariant {
+		return variant
+	}, nil)
+	pushClient.On("getServiceInstanceId").Return("myPushServiceInstanceId")
+	pushClient.On("getApplicationId").Return("myPushApplicationId")
+	pushClient.On("getBaseUrl").Return("http://example.org")
+	pushClient.On("getPushApplicationName", mock.Anything).Return("myPushAppName", nil)
+	annotationHelper.On("addAnnotationToMobileClient", mock.Anything, "myClientId", "http://example.org", "myPushApplicationId", "myPushAppName", "android", "myVariantId", "myServiceInstanceName").Once()
+
+	syncBindingSecret(&bindingSecret)
+
+	pushClient.AssertNotCalled(t, "createAndroidVariant", mock.Anything, mock.Anything)
+	pushClient.AssertCalled(t, "updateAndroidVariant", mock.Anything, mock.MatchedBy(func(variant *AndroidVariant) bool {
+		return variant.VariantID == "myVariantId" && variant.Secret == "myVariantSecret"
+	}))
+	kubeHelper.AssertCalled(t, "updatePushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return pushVariant.Status.VariantId == "myVariantId" && pushVariant.Status.ConfigSecretName == "mySecretName"
+	}))
+	annotationHelper.AssertExpectations(t)
+}
+
+func TestConfigOperator_reconcileDeletedBindings(t *testing.T) {
+	setup()
+
+	configSecret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "mySecretName",
+			Labels: map[string]string{
+				"clientId": "myClientId",
+			},
+			Annotations: map[string]string{
+				"binding/android": "myDeletedBindingId",
+				"binding/ios":     "myExistingBindingId",
+			},
+		},
+		Data: map[string][]byte{
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+			"config":              []byte("{\"android\":{\"variantId\":\"myAndroidVariantId\"},\"ios\":{\"variantId\":\"myIOSVariantId\"}}"),
+		},
+	}
+
+	bindings := &v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{
+		{Spec: v1beta1.ServiceBindingSpec{ExternalID: "myExistingBindingId"}},
+	}}
+
+	pushClient.On("getApplicationId").Return("myapp")
+	kubeHelper.On("listSecrets", mock.Anything, "serviceName=ups,pushApplicationId=myapp").Return(&v1.SecretList{Items: []v1.Secret{configSecret}}, nil)
+	kubeHelper.On("listServiceBindings", mock.Anything).Return(bindings, nil)
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(&configSecret, nil)
+	kubeHelper.On("updateSecret", mock.Anything, mock.Anything).Return(nil, nil)
+	annotationHelper.On("removeAnnotationFromMobileClient", mock.Anything, "myClientId", "android", "myServiceInstanceName").Once()
+	pushClient.On("deleteVariant", mock.Anything, "android", "myAndroidVariantId").Return(nil)
+
+	op.reconcileDeletedBindings(context.Background(), nil)
+
+	pushClient.AssertCalled(t, "deleteVariant", mock.Anything, "android", "myAndroidVariantId")
+	pushClient.AssertNotCalled(t, "deleteVariant", mock.Anything, "ios", mock.Anything)
+	kubeHelper.AssertCalled(t, "updateSecret", mock.Anything, mock.MatchedBy(func(secret *v1.Secret) bool {
+		return string(secret.Data["config"]) == "{\"ios\":{\"variantId\":\"myIOSVariantId\"}}"
+	}))
+	annotationHelper.AssertExpectations(t)
+}
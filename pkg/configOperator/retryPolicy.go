@@ -0,0 +1,78 @@
+package configOperator
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+// Controls how often failed UPS requests are retried. Only idempotent requests are retried.
+type RetryPolicy struct {
+	// Total number of attempts, including the first one
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func NewDefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    constants.UPSRequestMaxAttempts,
+		InitialBackoff: constants.UPSRetryInitialBackoff * time.Millisecond,
+		MaxBackoff:     constants.UPSRetryMaxBackoff * time.Millisecond,
+	}
+}
+
+// Returns the time to wait before the given retry (starting at 1). The backoff grows
+// exponentially and is randomized (full jitter) so that retries don't happen in lockstep.
+func (policy *RetryPolicy) backoff(retry int) time.Duration {
+	backoff := policy.InitialBackoff
+	for i := 1; i < retry && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// Returns the number of attempts for a request with the given method. Requests that
+// are not idempotent are only sent once, a retry could create a variant twice.
+func (policy *RetryPolicy) attemptsFor(method string) int {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		if policy.MaxAttempts > 1 {
+			return policy.MaxAttempts
+		}
+	}
+	return 1
+}
+
+// Waits for the given duration. Returns early with the context error when ctx is done.
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Derives a context that is cancelled after the timeout. A timeout of 0 disables it.
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,378 @@
+package configOperator
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	"github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+)
+
+type queueItemKind string
+
+const (
+	bindingSecretAdded     queueItemKind = "added binding secret"
+	bindingSecretDeleted   queueItemKind = "deleted binding secret"
+	serviceBindingDeleted  queueItemKind = "deleted service binding"
+	configSecretDeleting   queueItemKind = "deleting config secret"
+	pushVariantChanged     queueItemKind = "changed push variant"
+	pushVariantDeleted     queueItemKind = "deleted push variant"
+	pushApplicationChanged queueItemKind = "changed push application"
+	mobileClientDeleted    queueItemKind = "deleted mobile client"
+	// Shows that the worker isn't stuck
+	heartbeat queueItemKind = "heartbeat"
+)
+
+type queueItem struct {
+	kind queueItemKind
+	// The informer cache key of a binding secret, config secret, PushVariant or PushApplication,
+	// the ExternalID of a service binding or the name of a mobile client
+	key string
+	// Last known state of a deleted binding secret, it is no longer in the informer cache
+	secret *v1.Secret
+	// Last known state of a deleted PushVariant
+	pushVariant *v1alpha1.PushVariant
+}
+
+// Feeds the events of the secret, service binding, PushVariant, PushApplication and MobileClient informers into a rate
+// limited workqueue. Items that fail, e.g. because UPS is unavailable, are requeued with a backoff.
+// A single worker handles the queue so that updates of the same config secret don't race.
+type secretController struct {
+	op               ConfigOperator
+	queue            workqueue.RateLimitingInterface
+	bindingSecrets   cache.SharedIndexInformer
+	configSecrets    cache.SharedIndexInformer
+	credentials      cache.SharedIndexInformer
+	serviceBindings  cache.SharedIndexInformer
+	pushVariants     cache.SharedIndexInformer
+	pushApplications cache.SharedIndexInformer
+	mobileClients    cache.SharedIndexInformer
+}
+
+func newSecretController(op ConfigOperator) *secretController {
+	controller := new(secretController)
+	resync := constants.InformerResyncPeriod * time.Second
+
+	controller.op = op
+	controller.queue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "secrets")
+	controller.bindingSecrets = op.kubeHelper.newSecretInformer(constants.BindingSecretSelector, resync)
+	controller.configSecrets = op.kubeHelper.newSecretInformer(constants.ConfigSecretSelector, resync)
+	controller.credentials = op.kubeHelper.newSecretInformer(constants.CredentialsSecretSelector, resync)
+	controller.serviceBindings = op.kubeHelper.newServiceBindingInformer(resync)
+	controller.pushVariants = op.kubeHelper.newPushVariantInformer(resync)
+	controller.pushApplications = op.kubeHelper.newPushApplicationInformer(resync)
+	controller.mobileClients = op.kubeHelper.newMobileClientInformer(resync)
+
+	controller.bindingSecrets.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.enqueueBindingSecret,
+		UpdateFunc: controller.enqueueUpdatedBindingSecret,
+		DeleteFunc: controller.enqueueDeletedBindingSecret,
+	})
+	controller.configSecrets.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.enqueueDeletingConfigSecret,
+		UpdateFunc: controller.enqueueUpdatedConfigSecret,
+	})
+	controller.credentials.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: controller.enqueueUpdatedCredentials,
+	})
+	controller.serviceBindings.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: controller.enqueueDeletedServiceBinding,
+	})
+	controller.pushVariants.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.enqueuePushVariant,
+		UpdateFunc: controller.enqueueUpdatedPushVariant,
+		DeleteFunc: controller.enqueueDeletedPushVariant,
+	})
+	// Deletions of PushApplications are handled by their finalizer
+	controller.pushApplications.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    controller.enqueuePushApplication,
+		UpdateFunc: controller.enqueueUpdatedPushApplication,
+	})
+	controller.mobileClients.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: controller.enqueueDeletedMobileClient,
+	})
+
+	return controller
+}
+
+// Blocks until stop is closed and the item that is being handled is finished. Items that
+// are still queued are handled by the startup reconcile of the next run.
+func (controller *secretController) run(ctx context.Context, stop <-chan struct{}) {
+	go controller.bindingSecrets.Run(stop)
+	go controller.configSecrets.Run(stop)
+	go controller.credentials.Run(stop)
+	go controller.serviceBindings.Run(stop)
+	go controller.pushVariants.Run(stop)
+	go controller.pushApplications.Run(stop)
+	go controller.mobileClients.Run(stop)
+
+	log.Print("Waiting for the informer caches to sync")
+	if !cache.WaitForCacheSync(stop, controller.bindingSecrets.HasSynced, controller.configSecrets.HasSynced, controller.credentials.HasSynced, controller.serviceBindings.HasSynced, controller.pushVariants.HasSynced, controller.pushApplications.HasSynced, controller.mobileClients.HasSynced) {
+		controller.queue.ShutDown()
+		return
+	}
+
+	log.Print("Handling secrets, service bindings, PushVariants, PushApplications and MobileClients")
+	controller.op.health.setWatching(true)
+	defer controller.op.health.setWatching(false)
+	go controller.sendHeartbeats(stop)
+
+	worker := make(chan struct{})
+	go func() {
+		defer close(worker)
+		for !isStopped(stop) && controller.processNextItem(ctx) {
+		}
+	}()
+
+	<-stop
+	log.Print("Stopped handling new events")
+	controller.queue.ShutDown()
+	<-worker
+}
+
+// Returns false once the queue is shut down
+func (controller *secretController) processNextItem(ctx context.Context) bool {
+	obj, shutdown := controller.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer controller.queue.Done(obj)
+
+	item := obj.(queueItem)
+	err := controller.handle(ctx, item)
+	controller.op.health.watchProgressed()
+	if err == nil {
+		controller.queue.Forget(obj)
+		return true
+	}
+
+	// When shutting down the item is handled by the startup reconcile of the next run
+	if ctx.Err() == nil {
+		log.Printf("Error handling %s `%s`, retrying: %s", item.kind, item.key, err.Error())
+		controller.queue.AddRateLimited(obj)
+	}
+	return true
+}
+
+func (controller *secretController) handle(ctx context.Context, item queueItem) error {
+	switch item.kind {
+	case bindingSecretAdded:
+		obj, exists, err := controller.bindingSecrets.GetIndexer().GetByKey(item.key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// Already handled and deleted
+			return nil
+		}
+		return controller.op.handleAddSecret(ctx, obj.(*v1.Secret))
+	case bindingSecretDeleted:
+		controller.op.handleDeleteSecret(ctx, item.secret)
+	case configSecretDeleting:
+		obj, exists, err := controller.configSecrets.GetIndexer().GetByKey(item.key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// Already finalized
+			return nil
+		}
+		return controller.op.finalizeConfigSecret(ctx, obj.(*v1.Secret))
+	case serviceBindingDeleted:
+		for _, obj := range controller.configSecrets.GetStore().List() {
+			controller.op.removeVariantsOfDeletedBindings(ctx, obj.(*v1.Secret), func(bindingId string) bool {
+				return bindingId == item.key
+			})
+		}
+	case pushVariantChanged:
+		obj, exists, err := controller.pushVariants.GetIndexer().GetByKey(item.key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// The deletion is queued as well
+			return nil
+		}
+		return controller.op.syncPushVariant(ctx, obj.(*v1alpha1.PushVariant))
+	case pushVariantDeleted:
+		controller.op.handleDeletedPushVariant(ctx, item.pushVariant)
+	case pushApplicationChanged:
+		obj, exists, err := controller.pushApplications.GetIndexer().GetByKey(item.key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// The deletion is queued as well
+			return nil
+		}
+		return controller.op.syncPushApplication(ctx, obj.(*v1alpha1.PushApplication))
+	case mobileClientDeleted:
+		return controller.op.handleDeletedMobileClient(ctx, item.key)
+	}
+	return nil
+}
+
+// Queues a heartbeat in intervals, the worker handles it once it is done with the items before
+func (controller *secretController) sendHeartbeats(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(controller.op.health.heartbeatInterval()):
+			controller.queue.Add(queueItem{kind: heartbeat})
+		}
+	}
+}
+
+func (controller *secretController) enqueueBindingSecret(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Cannot handle binding secret: %s", err.Error())
+		return
+	}
+	controller.queue.Add(queueItem{kind: bindingSecretAdded, key: key})
+}
+
+// Resyncs are ignored, secrets that failed are already waiting in the queue
+func (controller *secretController) enqueueUpdatedBindingSecret(oldObj interface{}, newObj interface{}) {
+	if oldObj.(*v1.Secret).ResourceVersion == newObj.(*v1.Secret).ResourceVersion {
+		return
+	}
+	controller.enqueueBindingSecret(newObj)
+}
+
+func (controller *secretController) enqueueDeletedBindingSecret(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	controller.queue.Add(queueItem{kind: bindingSecretDeleted, key: secret.Name, secret: secret})
+}
+
+// Only config secrets that are being deleted are queued, the finalizer keeps them until their
+// variants have been deleted from UPS
+func (controller *secretController) enqueueDeletingConfigSecret(obj interface{}) {
+	secret, ok := obj.(*v1.Secret)
+	if !ok || secret.DeletionTimestamp == nil || !hasFinalizer(secret.ObjectMeta, constants.ConfigSecretFinalizer) {
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Cannot handle config secret: %s", err.Error())
+		return
+	}
+	controller.queue.Add(queueItem{kind: configSecretDeleting, key: key})
+}
+
+// Resyncs are ignored, secrets that failed are already waiting in the queue
+func (controller *secretController) enqueueUpdatedConfigSecret(oldObj interface{}, newObj interface{}) {
+	if oldObj.(*v1.Secret).ResourceVersion == newObj.(*v1.Secret).ResourceVersion {
+		return
+	}
+	controller.enqueueDeletingConfigSecret(newObj)
+}
+
+func (controller *secretController) enqueueDeletedServiceBinding(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	binding, ok := obj.(*v1beta1.ServiceBinding)
+	if !ok || binding.Spec.ExternalID == "" {
+		return
+	}
+	controller.queue.Add(queueItem{kind: serviceBindingDeleted, key: binding.Spec.ExternalID})
+}
+
+func (controller *secretController) enqueuePushVariant(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Cannot handle PushVariant: %s", err.Error())
+		return
+	}
+	controller.queue.Add(queueItem{kind: pushVariantChanged, key: key})
+}
+
+// Only changes of the spec are synced. Status updates of the operator itself and resyncs are
+// ignored, PushVariants that failed are already waiting in the queue.
+func (controller *secretController) enqueueUpdatedPushVariant(oldObj interface{}, newObj interface{}) {
+	if reflect.DeepEqual(oldObj.(*v1alpha1.PushVariant).Spec, newObj.(*v1alpha1.PushVariant).Spec) {
+		return
+	}
+	controller.enqueuePushVariant(newObj)
+}
+
+// The PushVariant named by the label of the credentials is synced when they change, e.g. when
+// they are edited or a binding is sent again with new credentials
+func (controller *secretController) enqueueUpdatedCredentials(oldObj interface{}, newObj interface{}) {
+	secret := newObj.(*v1.Secret)
+	if reflect.DeepEqual(oldObj.(*v1.Secret).Data, secret.Data) {
+		return
+	}
+
+	name := secret.Labels[constants.PushVariantLabelKey]
+	if name == "" {
+		return
+	}
+	key := name
+	if secret.Namespace != "" {
+		key = secret.Namespace + "/" + name
+	}
+	controller.queue.Add(queueItem{kind: pushVariantChanged, key: key})
+}
+
+func (controller *secretController) enqueueDeletedPushVariant(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	pushVariant, ok := obj.(*v1alpha1.PushVariant)
+	if !ok {
+		return
+	}
+	controller.queue.Add(queueItem{kind: pushVariantDeleted, key: pushVariant.Name, pushVariant: pushVariant})
+}
+
+func (controller *secretController) enqueuePushApplication(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Cannot handle PushApplication: %s", err.Error())
+		return
+	}
+	controller.queue.Add(queueItem{kind: pushApplicationChanged, key: key})
+}
+
+// Like PushVariants, only changes of the spec are synced. PushApplications that are being
+// deleted are synced to run the finalizer.
+func (controller *secretController) enqueueUpdatedPushApplication(oldObj interface{}, newObj interface{}) {
+	pushApplication := newObj.(*v1alpha1.PushApplication)
+	if pushApplication.DeletionTimestamp == nil && reflect.DeepEqual(oldObj.(*v1alpha1.PushApplication).Spec, pushApplication.Spec) {
+		return
+	}
+	controller.enqueuePushApplication(newObj)
+}
+
+func (controller *secretController) enqueueDeletedMobileClient(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	mobileClient, ok := obj.(*mobile.MobileClient)
+	if !ok {
+		return
+	}
+	controller.queue.Add(queueItem{kind: mobileClientDeleted, key: mobileClient.Name})
+}
@@ -0,0 +1,217 @@
+package configOperator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mobile "github.com/aerogear/mobile-crd-client/pkg/apis/mobile/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	"github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Returns an informer that lists the given objects and receives events from the fake watcher
+func newFakeInformer(list runtime.Object, objType runtime.Object, watcher *watch.FakeWatcher) cache.SharedIndexInformer {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}
+	return cache.NewSharedIndexInformer(listWatch, objType, 0, cache.Indexers{})
+}
+
+func waitFor(t *testing.T, calls chan string, expected string) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case call := <-calls:
+			if call == expected {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+}
+
+func TestSecretController_requeuesPushVariantsWhileUPSIsUnavailable(t *testing.T) {
+	setup()
+
+	pushVariant := push.PushVariant{
+		ObjectMeta: metav1.ObjectMeta{Name: "myClientId-android", Namespace: "myNamespace"},
+		Spec: push.PushVariantSpec{
+			Platform:  "android",
+			ClientId:  "myClientId",
+			SecretRef: v1.LocalObjectReference{Name: "myCredentials"},
+		},
+	}
+	credentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "myCredentials"},
+		Data: map[string][]byte{
+			"googleKey":     []byte("myGoogleKey"),
+			"projectNumber": []byte("myProjectNumber"),
+		},
+	}
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{Items: []push.PushVariant{pushVariant}}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	kubeHelper.On("getSecret", mock.Anything, "myCredentials").Return(credentials, nil)
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+
+	calls := make(chan string, 10)
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(nil, errUpsCircuitOpen).Run(func(args mock.Arguments) {
+		calls <- "createAndroidVariant"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSecretController(*op).run(ctx, ctx.Done())
+
+	// the first attempt and a retry
+	waitFor(t, calls, "createAndroidVariant")
+	waitFor(t, calls, "createAndroidVariant")
+	cancel()
+
+	kubeHelper.AssertCalled(t, "updatePushVariant", mock.Anything, mock.MatchedBy(func(pushVariant *push.PushVariant) bool {
+		return pushVariant.Status.LastError != ""
+	}))
+}
+
+func TestSecretController_removesVariantsOfDeletedServiceBindings(t *testing.T) {
+	setup()
+
+	configSecret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mySecretName",
+			Namespace: "myNamespace",
+			Labels: map[string]string{
+				"clientId":    "myClientId",
+				"serviceName": "ups",
+			},
+			Annotations: map[string]string{
+				"binding/android": "myServiceBindingId",
+			},
+		},
+		Data: map[string][]byte{
+			"serviceInstanceName": []byte("myServiceInstanceName"),
+			"config":              []byte("{\"android\":{\"variantId\":\"myVariantId\"}}"),
+		},
+	}
+
+	binding := v1beta1.ServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "myServiceBinding", Namespace: "myNamespace"},
+		Spec:       v1beta1.ServiceBindingSpec{ExternalID: "myServiceBindingId"},
+	}
+	bindingWatcher := watch.NewFake()
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{Items: []v1.Secret{configSecret}}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{Items: []v1beta1.ServiceBinding{binding}}, &v1beta1.ServiceBinding{}, bindingWatcher))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	// created before bindings were stored as PushVariants
+	kubeHelper.On("getPushVariant", mock.Anything, "myClientId-android").Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(&configSecret, nil)
+	kubeHelper.On("deleteSecret", mock.Anything, "mySecretName")
+	annotationHelper.On("removeAnnotationFromMobileClient", mock.Anything, "myClientId", "android", "myServiceInstanceName")
+
+	calls := make(chan string, 10)
+	pushClient.On("deleteVariant", mock.Anything, "android", "myVariantId").Return(nil).Run(func(args mock.Arguments) {
+		calls <- "deleteVariant"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go newSecretController(*op).run(ctx, ctx.Done())
+
+	bindingWatcher.Delete(&binding)
+
+	waitFor(t, calls, "deleteVariant")
+	cancel()
+
+	kubeHelper.AssertCalled(t, "deleteSecret", mock.Anything, "mySecretName")
+}
+
+func TestSecretController_finishesTheCurrentItemWhenStopped(t *testing.T) {
+	setup()
+
+	pushVariant := push.PushVariant{
+		ObjectMeta: metav1.ObjectMeta{Name: "myClientId-android", Namespace: "myNamespace"},
+		Spec: push.PushVariantSpec{
+			Platform:  "android",
+			ClientId:  "myClientId",
+			SecretRef: v1.LocalObjectReference{Name: "myCredentials"},
+		},
+	}
+	credentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "myCredentials"},
+		Data: map[string][]byte{
+			"googleKey":     []byte("myGoogleKey"),
+			"projectNumber": []byte("myProjectNumber"),
+		},
+	}
+
+	kubeHelper.On("newSecretInformer", "secretType=mobile-client-binding-secret", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "serviceName=ups", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newSecretInformer", "push.aerogear.org/push-variant", mock.Anything).Return(newFakeInformer(&v1.SecretList{}, &v1.Secret{}, watch.NewFake()))
+	kubeHelper.On("newServiceBindingInformer", mock.Anything).Return(newFakeInformer(&v1beta1.ServiceBindingList{}, &v1beta1.ServiceBinding{}, watch.NewFake()))
+	kubeHelper.On("newPushVariantInformer", mock.Anything).Return(newFakeInformer(&push.PushVariantList{Items: []push.PushVariant{pushVariant}}, &push.PushVariant{}, watch.NewFake()))
+	kubeHelper.On("newPushApplicationInformer", mock.Anything).Return(newFakeInformer(&push.PushApplicationList{}, &push.PushApplication{}, watch.NewFake()))
+	kubeHelper.On("newMobileClientInformer", mock.Anything).Return(newFakeInformer(&mobile.MobileClientList{}, &mobile.MobileClient{}, watch.NewFake()))
+	kubeHelper.On("getSecret", mock.Anything, "myCredentials").Return(credentials, nil)
+	kubeHelper.On("updatePushVariant", mock.Anything, mock.Anything).Return(nil, nil)
+	kubeHelper.On("findMobileClientConfig", mock.Anything, "myClientId").Return(nil, nil)
+	pushClient.On("getVariantsForPlatform", mock.Anything, mock.Anything).Return([]Variant{}, nil)
+
+	calls := make(chan string, 10)
+	release := make(chan struct{})
+	pushClient.On("createAndroidVariant", mock.Anything, mock.Anything).Return(nil, errUpsCircuitOpen).Run(func(args mock.Arguments) {
+		calls <- "createAndroidVariant"
+		<-release
+	})
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		newSecretController(*op).run(context.Background(), stop)
+		close(stopped)
+	}()
+
+	waitFor(t, calls, "createAndroidVariant")
+	close(stop)
+
+	select {
+	case <-stopped:
+		t.Fatal("controller stopped before the current item was handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the controller to stop")
+	}
+
+	// the failed item is left for the next run instead of being retried
+	pushClient.AssertNumberOfCalls(t, "createAndroidVariant", 1)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"k8s.io/api/core/v1"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -32,8 +33,31 @@ type IOSVariant struct {
 	Variant
 }
 
+// An iOS variant that authenticates against APNs with a .p8 key instead of a certificate
+type IOSTokenVariant struct {
+	PrivateKey string `json:"privateKey"`
+	KeyID      string `json:"keyId"`
+	TeamID     string `json:"teamId"`
+	BundleID   string `json:"bundleId"`
+	Production bool   `json:"production"`
+	Variant
+}
+
+// A Web Push variant for progressive web apps. UPS signs the pushes with the VAPID keys,
+// the subject is a `mailto:` or `https:` URL the push services can use to get in touch.
+type WebPushVariant struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+	Alias      string `json:"alias"`
+	Variant
+}
+
 type PushApplication struct {
-	ApplicationId string `json:"applicationId"`
+	ApplicationId string `json:"pushApplicationID,omitempty"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	// Secret the application authenticates with when sending pushes, set by UPS
+	MasterSecret string `json:"masterSecret,omitempty"`
 }
 
 func (this *AndroidVariant) getJson() ([]byte, error) {
@@ -63,14 +87,54 @@ func (this *IOSVariant) getJson() ([]byte, error) {
 	return buffer.Bytes(), err
 }
 
-type UPSClientConfig struct {
-	Android *map[string]string `json:"android,omitempty"`
-	IOS     *map[string]string `json:"ios,omitempty"`
+func (this *IOSTokenVariant) getJson() ([]byte, error) {
+	config := map[string]string{
+		"variantId":     this.VariantID,
+		"variantSecret": this.Secret,
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(config)
+	return buffer.Bytes(), err
+}
+
+// The public key is needed by the browser to subscribe to pushes
+func (this *WebPushVariant) getJson() ([]byte, error) {
+	config := map[string]string{
+		"variantId":     this.VariantID,
+		"variantSecret": this.Secret,
+		"appServerKey":  this.PublicKey,
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(config)
+	return buffer.Bytes(), err
+}
+
+// The `config` of a UPS config secret, maps platform names to the platform config
+type UPSClientConfig map[string]map[string]string
+
+// Returns the config of a platform. Platform names are matched case insensitive.
+func (config UPSClientConfig) get(platformName string) (map[string]string, bool) {
+	for name, platformConfig := range config {
+		if strings.EqualFold(name, platformName) {
+			return platformConfig, true
+		}
+	}
+	return nil, false
 }
 
 type VariantServiceBindingMapping struct {
 	VariantId        string
 	ServiceBindingId string
+	// Name of the mobile client of the config secret
+	ClientId string
+	// Name of the platform of the variant, e.g. `android`
+	Platform string
 }
 
 func GetClientConfigRepresentation(variantId, serviceBindingId string) (VariantServiceBindingMapping, error) {
@@ -0,0 +1,186 @@
+package configOperator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"github.com/pkg/errors"
+)
+
+// Refresh Keycloak tokens this long before they actually expire
+const tokenExpiryMargin = 30 * time.Second
+
+// Adds credentials to the requests sent to UPS
+type UpsAuthenticator interface {
+	authenticate(req *http.Request) error
+}
+
+// Sends anonymous requests. Used when UPS has no authentication enabled.
+type NoAuthenticator struct{}
+
+func (auth *NoAuthenticator) authenticate(req *http.Request) error {
+	return nil
+}
+
+// Uses HTTP basic authentication
+type BasicAuthenticator struct {
+	username string
+	password string
+}
+
+func NewBasicAuthenticator(username string, password string) *BasicAuthenticator {
+	auth := new(BasicAuthenticator)
+
+	auth.username = username
+	auth.password = password
+
+	return auth
+}
+
+func (auth *BasicAuthenticator) authenticate(req *http.Request) error {
+	req.SetBasicAuth(auth.username, auth.password)
+	return nil
+}
+
+// Obtains bearer tokens from Keycloak using the OAuth2 client credentials flow.
+// Tokens are cached and refreshed shortly before they expire.
+type KeycloakAuthenticator struct {
+	tokenUrl     string
+	clientId     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+	now    func() time.Time
+}
+
+func NewKeycloakAuthenticator(keycloakUrl string, realm string, clientId string, clientSecret string) *KeycloakAuthenticator {
+	tokenUrl := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", keycloakUrl, url.PathEscape(realm))
+	return newKeycloakAuthenticatorForTokenUrl(tokenUrl, clientId, clientSecret)
+}
+
+func newKeycloakAuthenticatorForTokenUrl(tokenUrl string, clientId string, clientSecret string) *KeycloakAuthenticator {
+	auth := new(KeycloakAuthenticator)
+
+	auth.tokenUrl = tokenUrl
+	auth.clientId = clientId
+	auth.clientSecret = clientSecret
+	auth.httpClient = &http.Client{}
+	auth.now = time.Now
+
+	return auth
+}
+
+// The token request is bound to the context of req
+func (auth *KeycloakAuthenticator) authenticate(req *http.Request) error {
+	token, err := auth.getToken(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Returns the cached token or requests a new one if it is about to expire
+func (auth *KeycloakAuthenticator) getToken(ctx context.Context) (string, error) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+
+	if auth.token != "" && auth.now().Add(tokenExpiryMargin).Before(auth.expiry) {
+		return auth.token, nil
+	}
+
+	log.Printf("Requesting a new access token from %s", auth.tokenUrl)
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {auth.clientId},
+		"client_secret": {auth.clientSecret},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, auth.tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	requestedAt := auth.now()
+	resp, err := auth.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "error requesting access token")
+	}
+
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("token endpoint responded with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	err = json.Unmarshal(body, &tokenResponse)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid token response")
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("token response does not contain an access token")
+	}
+
+	auth.token = tokenResponse.AccessToken
+	auth.expiry = requestedAt.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+
+	return auth.token, nil
+}
+
+// Picks the authentication method based on the keys present in the UPS secret.
+// Keycloak takes precedence over basic auth. Without any credentials requests are sent anonymously.
+func newUpsAuthenticator(data map[string][]byte) (UpsAuthenticator, error) {
+	keycloakUrl := string(data[constants.UpsSecretDataKeycloakUrlKey])
+	username := string(data[constants.UpsSecretDataUsernameKey])
+
+	if keycloakUrl != "" {
+		keycloakUrl, err := parseUpsUrl(keycloakUrl)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid `%s`", constants.UpsSecretDataKeycloakUrlKey)
+		}
+
+		realm := string(data[constants.UpsSecretDataKeycloakRealmKey])
+		clientId := string(data[constants.UpsSecretDataKeycloakClientIdKey])
+		clientSecret := string(data[constants.UpsSecretDataKeycloakClientSecretKey])
+
+		var missing []string
+		if realm == "" {
+			missing = append(missing, constants.UpsSecretDataKeycloakRealmKey)
+		}
+		if clientId == "" {
+			missing = append(missing, constants.UpsSecretDataKeycloakClientIdKey)
+		}
+		if len(missing) > 0 {
+			return nil, errors.Errorf("keycloak authentication requires %s", strings.Join(missing, ", "))
+		}
+
+		return NewKeycloakAuthenticator(keycloakUrl, realm, clientId, clientSecret), nil
+	}
+
+	if username != "" {
+		return NewBasicAuthenticator(username, string(data[constants.UpsSecretDataPasswordKey])), nil
+	}
+
+	return &NoAuthenticator{}, nil
+}
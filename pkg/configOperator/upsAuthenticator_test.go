@@ -0,0 +1,121 @@
+package configOperator
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Stand-in for the Keycloak token endpoint that hands out numbered tokens
+func newTokenServer(t *testing.T, expiresIn int, issued *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" ||
+			r.Form.Get("client_id") != "myClientId" ||
+			r.Form.Get("client_secret") != "myClientSecret" {
+			t.Errorf("unexpected token request: %v", r.Form)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		*issued++
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%d,"token_type":"bearer"}`, *issued, expiresIn)
+	}))
+}
+
+func TestKeycloakAuthenticator_cachesAndRefreshesTokens(t *testing.T) {
+	issued := 0
+	server := newTokenServer(t, 300, &issued)
+	defer server.Close()
+
+	now := time.Now()
+	auth := newKeycloakAuthenticatorForTokenUrl(server.URL, "myClientId", "myClientSecret")
+	auth.now = func() time.Time { return now }
+
+	assertToken := func(expected string) {
+		req, _ := http.NewRequest(http.MethodGet, "http://ups.example.org", nil)
+		if err := auth.authenticate(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if header := req.Header.Get("Authorization"); header != "Bearer "+expected {
+			t.Errorf("expected bearer token `%s` but got header `%s`", expected, header)
+		}
+	}
+
+	assertToken("token-1")
+
+	// still valid, no new token requested
+	now = now.Add(4 * time.Minute)
+	assertToken("token-1")
+
+	// about to expire, refreshed in advance
+	now = now.Add(40 * time.Second)
+	assertToken("token-2")
+
+	if issued != 2 {
+		t.Errorf("expected 2 token requests but got %d", issued)
+	}
+}
+
+func TestKeycloakAuthenticator_failsOnRejectedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"unauthorized_client"}`))
+	}))
+	defer server.Close()
+
+	auth := newKeycloakAuthenticatorForTokenUrl(server.URL, "myClientId", "wrongSecret")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://ups.example.org", nil)
+	if err := auth.authenticate(req); err == nil {
+		t.Error("expected an error when the token endpoint rejects the credentials")
+	}
+	if header := req.Header.Get("Authorization"); header != "" {
+		t.Errorf("expected no authorization header but got `%s`", header)
+	}
+}
+
+func TestNewUpsAuthenticator(t *testing.T) {
+	auth, err := newUpsAuthenticator(map[string][]byte{})
+	if _, ok := auth.(*NoAuthenticator); !ok || err != nil {
+		t.Errorf("expected anonymous requests without credentials, got %T (%v)", auth, err)
+	}
+
+	auth, err = newUpsAuthenticator(map[string][]byte{
+		"username": []byte("admin"),
+		"password": []byte("secret"),
+	})
+	if _, ok := auth.(*BasicAuthenticator); !ok || err != nil {
+		t.Errorf("expected basic auth, got %T (%v)", auth, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://ups.example.org", nil)
+	auth.authenticate(req)
+	if username, password, ok := req.BasicAuth(); !ok || username != "admin" || password != "secret" {
+		t.Errorf("expected basic auth credentials to be set, got `%s`:`%s`", username, password)
+	}
+
+	auth, err = newUpsAuthenticator(map[string][]byte{
+		"keycloakUrl":          []byte("https://sso.example.org/auth/"),
+		"keycloakRealm":        []byte("myRealm"),
+		"keycloakClientId":     []byte("myClientId"),
+		"keycloakClientSecret": []byte("myClientSecret"),
+		"username":             []byte("ignored"),
+	})
+	keycloak, ok := auth.(*KeycloakAuthenticator)
+	if !ok || err != nil {
+		t.Fatalf("expected keycloak auth, got %T (%v)", auth, err)
+	}
+	if keycloak.tokenUrl != "https://sso.example.org/auth/realms/myRealm/protocol/openid-connect/token" {
+		t.Errorf("unexpected token url `%s`", keycloak.tokenUrl)
+	}
+
+	_, err = newUpsAuthenticator(map[string][]byte{
+		"keycloakUrl": []byte("https://sso.example.org/auth"),
+	})
+	if err == nil {
+		t.Error("expected an error when the keycloak realm and client are missing")
+	}
+}
@@ -2,232 +2,451 @@ package configOperator
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
+// All methods that talk to UPS return an *UpsError when the request fails.
+// Use the IsUps... helpers to check the reason.
 type UpsClient interface {
-	getPushApplicationName() (string, error)
-	getVariants() ([]Variant, error)
-	hasAndroidVariant(key string) *AndroidVariant
-	createAndroidVariant(variant *AndroidVariant) (bool, *AndroidVariant)
-	createIOSVariant(variant *IOSVariant) (bool, *IOSVariant)
-	deleteVariant(platform string, variantId string) bool
+	getPushApplicationName(ctx context.Context) (string, error)
+	getVariants(ctx context.Context) ([]Variant, error)
+	// Lists the variants of a UPS resource, e.g. `android` or `ios_token`
+	getVariantsForPlatform(ctx context.Context, platform string) ([]Variant, error)
+	// Returns the variant with the given ID of a UPS resource, a not found error if there is none
+	getVariant(ctx context.Context, platform string, variantId string) (*Variant, error)
+	hasAndroidVariant(ctx context.Context, key string) (*AndroidVariant, error)
+	createAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error)
+	createIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error)
+	// Replaces the credentials of an existing variant, the variant ID and secret stay the same
+	updateAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error)
+	updateIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error)
+	createIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error)
+	updateIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error)
+	createWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error)
+	updateWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error)
+	deleteVariant(ctx context.Context, platform string, variantId string) error
+	// Manage push applications, independently of the application of the client
+	getApplication(ctx context.Context, applicationId string) (*PushApplication, error)
+	createApplication(ctx context.Context, application *PushApplication) (*PushApplication, error)
+	updateApplication(ctx context.Context, application *PushApplication) (*PushApplication, error)
+	deleteApplication(ctx context.Context, applicationId string) error
 	getApplicationId() string
 	getServiceInstanceId() string
 	getBaseUrl() string
+	// Returns false while the circuit breaker considers UPS to be unavailable
+	isAvailable() bool
 }
 
 type UpsClientImpl struct {
 	config            *PushApplication
 	serviceInstanceId string
-	baseUrl           string
+	// URL of the UPS admin console
+	baseUrl string
+	// URL of the UPS REST API, e.g. `https://ups.example.com/rest`
+	restUrl       string
+	authenticator UpsAuthenticator
+	httpClient    *http.Client
+	// Maximum duration of a single request, retries are not included
+	requestTimeout time.Duration
+	retryPolicy    *RetryPolicy
+	breaker        *CircuitBreaker
+	sleep          func(ctx context.Context, duration time.Duration) error
 }
 
-func NewUpsClientImpl(config *PushApplication, serviceInstanceId string, baseUrl string) *UpsClientImpl {
+func NewUpsClientImpl(config *PushApplication, serviceInstanceId string, baseUrl string, restUrl string, authenticator UpsAuthenticator, requestTimeout time.Duration) *UpsClientImpl {
 	client := new(UpsClientImpl)
 
 	client.config = config
 	client.serviceInstanceId = serviceInstanceId
 	client.baseUrl = baseUrl
+	client.restUrl = restUrl
+	client.authenticator = authenticator
+	client.httpClient = &http.Client{}
+	client.requestTimeout = requestTimeout
+	client.retryPolicy = NewDefaultRetryPolicy()
+	client.breaker = NewDefaultCircuitBreaker()
+	client.sleep = sleepWithContext
+
+	if client.authenticator == nil {
+		client.authenticator = &NoAuthenticator{}
+	}
 
 	return client
 }
 
-const BaseUrl = "http://localhost:8080/rest/applications"
-
 // fetches the push application name from the UPS system
-func (client *UpsClientImpl) getPushApplicationName() (string, error) {
-	url := fmt.Sprintf("%s/%s", BaseUrl, client.config.ApplicationId)
-	log.Printf("UPS request: %s", url)
-
-	resp, err := http.Get(url)
+func (client *UpsClientImpl) getPushApplicationName(ctx context.Context) (string, error) {
+	body, err := client.send(ctx, http.MethodGet, client.applicationUrl(), "", nil)
 	if err != nil {
 		return "", err
 	}
 
-	defer resp.Body.Close()
-	body, _ := ioutil.ReadAll(resp.Body)
-
-	var pushAppInfo map[string]json.RawMessage
+	var pushAppInfo struct {
+		Name string `json:"name"`
+	}
 	err = json.Unmarshal(body, &pushAppInfo)
 	if err != nil {
-		return "", err
+		return "", errors.Wrap(err, "invalid push application returned by UPS")
 	}
 
-	appNameWithQuotes := string(pushAppInfo["name"])            // --> e.g. "foo"
-	appName := appNameWithQuotes[1 : len(appNameWithQuotes)-1]	// strip the quotes --> foo
+	return pushAppInfo.Name, nil
+}
+
+func (client *UpsClientImpl) deleteVariant(ctx context.Context, platform string, variantId string) error {
+	log.Printf("Deleting %s variant with id `%s`", platform, variantId)
+
+	_, err := client.send(ctx, http.MethodDelete, client.applicationUrl(platform, variantId), "", nil)
+	if err != nil {
+		return err
+	}
 
-	return appName, nil
+	log.Printf("Variant `%s` has been deleted", variantId)
+	return nil
 }
 
-func (client *UpsClientImpl) deleteVariant(platform string, variantId string) bool {
-	variant := client.hasVariant(platform, variantId)
+func (client *UpsClientImpl) getApplication(ctx context.Context, applicationId string) (*PushApplication, error) {
+	body, err := client.send(ctx, http.MethodGet, client.applicationsUrl(applicationId), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var application PushApplication
+	err = json.Unmarshal(body, &application)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid push application returned by UPS")
+	}
+
+	return &application, nil
+}
 
-	if variant != nil {
-		log.Printf("Deleting %s variant with id `%s`", platform, variant.VariantID)
+func (client *UpsClientImpl) createApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	payload, err := json.Marshal(application)
+	if err != nil {
+		return nil, err
+	}
 
-		url := fmt.Sprintf("%s/%s/%s/%s", BaseUrl, client.config.ApplicationId,
-			platform, variant.VariantID)
+	body, err := client.send(ctx, http.MethodPost, client.applicationsUrl(), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
 
-		log.Printf("UPS request: %s", url)
+	var createdApplication PushApplication
+	err = json.Unmarshal(body, &createdApplication)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid push application returned by UPS")
+	}
 
-		req, err := http.NewRequest(http.MethodDelete, url, nil)
+	log.Printf("Push application `%s` has been created", createdApplication.ApplicationId)
+	return &createdApplication, nil
+}
 
-		httpClient := http.Client{}
-		resp, err := httpClient.Do(req)
+// Updates the name and description, UPS ignores the other fields
+func (client *UpsClientImpl) updateApplication(ctx context.Context, application *PushApplication) (*PushApplication, error) {
+	payload, err := json.Marshal(application)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPut, client.applicationsUrl(application.ApplicationId), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedApplication := *application
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &updatedApplication)
 		if err != nil {
-			log.Fatal(err.Error())
-			return false
+			return nil, errors.Wrap(err, "invalid push application returned by UPS")
 		}
-
-		log.Printf("Variant `%s` has been deleted (status code %d)", variant.VariantID, resp.StatusCode)
-		return resp.StatusCode == 204
 	}
 
-	log.Printf("No variant found to delete (Variant Id: `%s`)", variantId)
-	return false
+	return &updatedApplication, nil
 }
 
-// Find an Android Variant by its Google Key
-func (client *UpsClientImpl) hasAndroidVariant(key string) *AndroidVariant {
-	variants, err := client.getAndroidVariants()
+func (client *UpsClientImpl) deleteApplication(ctx context.Context, applicationId string) error {
+	log.Printf("Deleting push application `%s`", applicationId)
 
+	_, err := client.send(ctx, http.MethodDelete, client.applicationsUrl(applicationId), "", nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	log.Printf("Push application `%s` has been deleted", applicationId)
+	return nil
+}
 
-		// Return true here to prevent creating a new variant when the
-		// request fails
-		return &AndroidVariant{}
+// Find an Android Variant by its Google Key. Returns nil if there is no such variant.
+func (client *UpsClientImpl) hasAndroidVariant(ctx context.Context, key string) (*AndroidVariant, error) {
+	variants, err := client.getAndroidVariants(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, variant := range variants {
 		if variant.GoogleKey == key {
-			return &variant
+			return &variant, nil
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (client *UpsClientImpl) createAndroidVariant(variant *AndroidVariant) (bool, *AndroidVariant) {
-	url := fmt.Sprintf("%s/%s/android", BaseUrl, client.config.ApplicationId)
-	log.Printf("UPS request: %s", url)
-
+func (client *UpsClientImpl) createAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
 	payload, err := json.Marshal(variant)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
 	log.Println("UPS Payload", string(payload))
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	httpClient := http.Client{}
-	resp, err := httpClient.Do(req)
+	body, err := client.send(ctx, http.MethodPost, client.applicationUrl("android"), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
 
+	var createdVariant AndroidVariant
+	err = json.Unmarshal(body, &createdVariant)
 	if err != nil {
-		panic(err.Error())
+		return nil, errors.Wrap(err, "invalid android variant returned by UPS")
 	}
 
-	log.Printf("UPS responded with status code : %s", string(resp.StatusCode))
+	return &createdVariant, nil
+}
 
-	defer resp.Body.Close()
-	body, _ := ioutil.ReadAll(resp.Body)
-	var createdVariant AndroidVariant
-	json.Unmarshal(body, &createdVariant)
+func (client *UpsClientImpl) updateAndroidVariant(ctx context.Context, variant *AndroidVariant) (*AndroidVariant, error) {
+	payload, err := json.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPut, client.applicationUrl("android", variant.VariantID), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedVariant := *variant
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &updatedVariant)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid android variant returned by UPS")
+		}
+	}
 
-	return resp.StatusCode == 201, &createdVariant
+	return &updatedVariant, nil
 }
 
-func (client *UpsClientImpl) createIOSVariant(variant *IOSVariant) (bool, *IOSVariant) {
-	url := fmt.Sprintf("%s/%s/ios", BaseUrl, client.config.ApplicationId)
-	log.Printf("UPS request: %s", url)
+func (client *UpsClientImpl) createIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	body, contentType, err := buildIOSVariantForm(variant)
+	if err != nil {
+		return nil, err
+	}
 
-	production := "true"
-	if !variant.Production {
-		production = "false"
+	b, err := client.send(ctx, http.MethodPost, client.applicationUrl("ios"), contentType, body)
+	if err != nil {
+		return nil, err
 	}
 
-	params := map[string]string{
-		"name":        variant.Name,
-		"passphrase":  variant.Passphrase,
-		"production":  production,
-		"description": variant.Description,
+	var createdVariant IOSVariant
+	err = json.Unmarshal(b, &createdVariant)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid iOS variant returned by UPS")
 	}
 
-	// We need to decode it before sending
-	decodedString, err := base64.StdEncoding.DecodeString(string(variant.Certificate))
+	return &createdVariant, nil
+}
+
+func (client *UpsClientImpl) updateIOSVariant(ctx context.Context, variant *IOSVariant) (*IOSVariant, error) {
+	body, contentType, err := buildIOSVariantForm(variant)
 	if err != nil {
-		log.Print("Invalid cert - Please check this cert is in base64 encoded format: ", err)
+		return nil, err
 	}
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("certificate", "certificate")
+	b, err := client.send(ctx, http.MethodPut, client.applicationUrl("ios", variant.VariantID), contentType, body)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
-	part.Write(decodedString)
 
-	for key, val := range params {
-		_ = writer.WriteField(key, val)
+	updatedVariant := *variant
+	if len(b) > 0 {
+		err = json.Unmarshal(b, &updatedVariant)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid iOS variant returned by UPS")
+		}
 	}
 
-	defer writer.Close()
+	return &updatedVariant, nil
+}
 
-	req, err := http.NewRequest(http.MethodPost, url, body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Accept", "application/json")
-	httpClient := http.Client{}
-	resp, err := httpClient.Do(req)
+// iOS variants that use token based authentication live under their own `ios_token` resource
+func (client *UpsClientImpl) createIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	payload, err := json.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPost, client.applicationUrl("ios_token"), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
 
+	var createdVariant IOSTokenVariant
+	err = json.Unmarshal(body, &createdVariant)
 	if err != nil {
-		panic(err.Error())
+		return nil, errors.Wrap(err, "invalid iOS token variant returned by UPS")
 	}
 
-	log.Printf("UPS responded with status code: %s ", string(resp.StatusCode))
+	return &createdVariant, nil
+}
 
-	defer resp.Body.Close()
-	b, _ := ioutil.ReadAll(resp.Body)
-	var createdVariant IOSVariant
-	json.Unmarshal(b, &createdVariant)
-	return resp.StatusCode == 201, &createdVariant
+func (client *UpsClientImpl) updateIOSTokenVariant(ctx context.Context, variant *IOSTokenVariant) (*IOSTokenVariant, error) {
+	payload, err := json.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPut, client.applicationUrl("ios_token", variant.VariantID), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedVariant := *variant
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &updatedVariant)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid iOS token variant returned by UPS")
+		}
+	}
+
+	return &updatedVariant, nil
+}
+
+func (client *UpsClientImpl) createWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	payload, err := json.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPost, client.applicationUrl("web_push"), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdVariant WebPushVariant
+	err = json.Unmarshal(body, &createdVariant)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid web push variant returned by UPS")
+	}
+
+	return &createdVariant, nil
+}
+
+func (client *UpsClientImpl) updateWebPushVariant(ctx context.Context, variant *WebPushVariant) (*WebPushVariant, error) {
+	payload, err := json.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := client.send(ctx, http.MethodPut, client.applicationUrl("web_push", variant.VariantID), "application/json", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedVariant := *variant
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &updatedVariant)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid web push variant returned by UPS")
+		}
+	}
+
+	return &updatedVariant, nil
 }
 
-func (client *UpsClientImpl) getVariantsForPlatform(platform string) ([]Variant, error) {
-	variantBytes, err := client.getVariantsForPlatformRaw(platform)
+func (client *UpsClientImpl) getVariantsForPlatform(ctx context.Context, platform string) ([]Variant, error) {
+	variantBytes, err := client.getVariantsForPlatformRaw(ctx, platform)
 
 	if err != nil {
 		return nil, err
 	}
 
 	variants := make([]Variant, 0)
-	json.Unmarshal(variantBytes, &variants)
+	err = json.Unmarshal(variantBytes, &variants)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid list of %s variants returned by UPS", platform)
+	}
 
 	return variants, nil
 }
 
-func (client *UpsClientImpl) getVariants() ([]Variant, error) {
+func (client *UpsClientImpl) getVariant(ctx context.Context, platform string, variantId string) (*Variant, error) {
+	body, err := client.send(ctx, http.MethodGet, client.applicationUrl(platform, variantId), "", nil)
+	if err != nil {
+		return nil, err
+	}
 
-	UPSIOSVariants, err := client.getVariantsForPlatform("ios")
-	UPSAndroidVariants, err := client.getVariantsForPlatform("android")
+	variant := &Variant{}
+	err = json.Unmarshal(body, variant)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s variant returned by UPS", platform)
+	}
+
+	return variant, nil
+}
+
+func (client *UpsClientImpl) getVariants(ctx context.Context) ([]Variant, error) {
+	UPSIOSVariants, err := client.getVariantsForPlatform(ctx, "ios")
+	if err != nil {
+		return nil, err
+	}
 
+	UPSAndroidVariants, err := client.getVariantsForPlatform(ctx, "android")
 	if err != nil {
 		return nil, err
 	}
 
+	// Older UPS versions don't support token based iOS and web push variants
+	UPSIOSTokenVariants, err := client.getVariantsForPlatform(ctx, "ios_token")
+	if IsUpsNotFound(err) {
+		UPSIOSTokenVariants = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	UPSWebPushVariants, err := client.getVariantsForPlatform(ctx, "web_push")
+	if IsUpsNotFound(err) {
+		UPSWebPushVariants = nil
+	} else if err != nil {
+		return nil, err
+	}
+
 	variants := append(UPSAndroidVariants, UPSIOSVariants...)
+	variants = append(variants, UPSIOSTokenVariants...)
+	variants = append(variants, UPSWebPushVariants...)
 
 	return variants, nil
 }
 
+// Returns a client for the variants of another push application. The clients share the
+// authentication and the circuit breaker, since they talk to the same UPS.
+func (client *UpsClientImpl) withApplicationId(applicationId string) *UpsClientImpl {
+	applicationClient := *client
+	applicationClient.config = &PushApplication{ApplicationId: applicationId}
+	return &applicationClient
+}
+
 func (client *UpsClientImpl) getApplicationId() string {
 	return client.config.ApplicationId
 }
@@ -240,49 +459,201 @@ func (client *UpsClientImpl) getBaseUrl() string {
 	return client.baseUrl
 }
 
+func (client *UpsClientImpl) isAvailable() bool {
+	return client.breaker.getState() != CircuitBreakerOpen
+}
+
 ////////////////////////////////////// internal things /////////////////////////////////////
 
-func (client *UpsClientImpl) getAndroidVariants() ([]AndroidVariant, error) {
-	variantsBytes, err := client.getVariantsForPlatformRaw("android")
+// Sends a request to UPS and returns the response body. Idempotent requests are retried
+// while UPS is unavailable. Transport errors and unsuccessful responses are returned as *UpsError,
+// the context error is returned as is if ctx is done.
+func (client *UpsClientImpl) send(ctx context.Context, method string, url string, contentType string, body []byte) ([]byte, error) {
+	attempts := client.retryPolicy.attemptsFor(method)
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			backoff := client.retryPolicy.backoff(attempt - 1)
+			log.Printf("Retrying UPS request %s %s in %s (attempt %d of %d)", method, url, backoff, attempt, attempts)
+			if sleepErr := client.sleep(ctx, backoff); sleepErr != nil {
+				return nil, sleepErr
+			}
+		}
+
+		var respBody []byte
+		respBody, err = client.sendThroughBreaker(ctx, method, url, contentType, body)
+
+		// Retrying only makes sense if UPS could not handle the request
+		if !IsUpsUnavailable(err) || err == errUpsCircuitOpen {
+			return respBody, err
+		}
+	}
+
+	return nil, err
+}
+
+// Sends a request unless the circuit breaker is open and records the outcome
+func (client *UpsClientImpl) sendThroughBreaker(ctx context.Context, method string, url string, contentType string, body []byte) ([]byte, error) {
+	if !client.breaker.allow() {
+		return nil, errUpsCircuitOpen
+	}
+
+	respBody, err := client.sendOnce(ctx, method, url, contentType, body)
+	if IsUpsUnavailable(err) {
+		client.breaker.recordFailure()
+	} else if ctx.Err() != nil {
+		// Cancelled by the operator, this says nothing about the health of UPS
+		client.breaker.recordCancellation()
+	} else {
+		client.breaker.recordSuccess()
+	}
+
+	return respBody, err
+}
+
+// Sends a single request to UPS using the configured authentication
+func (client *UpsClientImpl) sendOnce(ctx context.Context, method string, url string, contentType string, body []byte) ([]byte, error) {
+	log.Printf("UPS request: %s %s", method, url)
+
+	requestCtx, cancel := withOptionalTimeout(ctx, client.requestTimeout)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, bodyReader)
 	if err != nil {
 		return nil, err
 	}
-	androidVariants := make([]AndroidVariant, 0)
-	json.Unmarshal(variantsBytes, &androidVariants)
-	return androidVariants, nil
+	req = req.WithContext(requestCtx)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	err = client.authenticator.authenticate(req)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if err != nil {
+		return nil, newUpsUnauthorizedError(err)
+	}
+
+	start := time.Now()
+	status := upsRequestStatusError
+	defer func() {
+		upsRequestDuration.WithLabelValues(method, client.endpointLabel(url), status).Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := client.httpClient.Do(req)
+	if ctx.Err() != nil {
+		status = upsRequestStatusCancelled
+		return nil, ctx.Err()
+	} else if err != nil {
+		// this includes requests that exceeded the request timeout
+		return nil, newUpsUnavailableError(err)
+	}
+	status = strconv.Itoa(resp.StatusCode)
+
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if err != nil {
+		return nil, newUpsUnavailableError(err)
+	}
+
+	log.Printf("UPS responded with status code: %d", resp.StatusCode)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newUpsResponseError(resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// Builds the URL of a resource that belongs to the push application, e.g.
+// `<restUrl>/applications/<applicationId>/android/<variantId>`
+func (client *UpsClientImpl) applicationUrl(segments ...string) string {
+	return client.applicationsUrl(append([]string{client.config.ApplicationId}, segments...)...)
+}
+
+// Builds the URL of the push applications or one of them, e.g. `<restUrl>/applications/<applicationId>`
+func (client *UpsClientImpl) applicationsUrl(segments ...string) string {
+	path := []string{client.restUrl, "applications"}
+	for _, segment := range segments {
+		path = append(path, url.PathEscape(segment))
+	}
+	return strings.Join(path, "/")
 }
 
-// Find a Variant by its variant id
-func (client *UpsClientImpl) hasVariant(platform string, variantId string) *Variant {
-	variants, err := client.getVariantsForPlatform(platform)
+// Returns the path of a URL built by applicationUrl with the IDs replaced, e.g.
+// `applications/:id/android/:id`. Keeps the number of metric labels small.
+func (client *UpsClientImpl) endpointLabel(requestUrl string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(requestUrl, client.restUrl), "/"), "/")
+	for i := 1; i < len(segments); i += 2 {
+		segments[i] = ":id"
+	}
+	return strings.Join(segments, "/")
+}
 
+// UPS expects iOS variants as a multipart form that contains the certificate
+func buildIOSVariantForm(variant *IOSVariant) ([]byte, string, error) {
+	production := "true"
+	if !variant.Production {
+		production = "false"
+	}
+
+	params := map[string]string{
+		"name":        variant.Name,
+		"passphrase":  variant.Passphrase,
+		"production":  production,
+		"description": variant.Description,
+	}
+
+	// We need to decode it before sending
+	decodedString, err := base64.StdEncoding.DecodeString(string(variant.Certificate))
 	if err != nil {
-		log.Fatal(err)
+		return nil, "", errors.Wrap(err, "invalid cert - please check this cert is in base64 encoded format")
+	}
 
-		// Return true here to prevent creating a new variant when the
-		// request fails
-		return &Variant{}
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("certificate", "certificate")
+	if err != nil {
+		return nil, "", err
 	}
+	part.Write(decodedString)
 
-	for _, variant := range variants {
-		if variant.VariantID == variantId {
-			return &variant
-		}
+	for key, val := range params {
+		_ = writer.WriteField(key, val)
 	}
 
-	return nil
-}
+	// Writes the closing boundary, must happen before the request is sent
+	err = writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
 
-func (client *UpsClientImpl) getVariantsForPlatformRaw(platform string) ([]byte, error) {
-	url := fmt.Sprintf("%s/%s/%s", BaseUrl, client.config.ApplicationId, platform)
-	log.Printf("UPS request: %s", url)
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
 
-	resp, err := http.Get(url)
+func (client *UpsClientImpl) getAndroidVariants(ctx context.Context) ([]AndroidVariant, error) {
+	variantsBytes, err := client.getVariantsForPlatformRaw(ctx, "android")
 	if err != nil {
 		return nil, err
 	}
+	androidVariants := make([]AndroidVariant, 0)
+	err = json.Unmarshal(variantsBytes, &androidVariants)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid list of android variants returned by UPS")
+	}
+	return androidVariants, nil
+}
 
-	defer resp.Body.Close()
-	body, _ := ioutil.ReadAll(resp.Body)
-	return body, nil
+func (client *UpsClientImpl) getVariantsForPlatformRaw(ctx context.Context, platform string) ([]byte, error) {
+	return client.send(ctx, http.MethodGet, client.applicationUrl(platform), "", nil)
 }
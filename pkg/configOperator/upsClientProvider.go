@@ -1,36 +1,128 @@
 package configOperator
 
 import (
-	"os"
+	"context"
 	"github.com/aerogear/ups-config-operator/pkg/constants"
 	"k8s.io/client-go/kubernetes"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	pushv1alpha1 "github.com/aerogear/ups-config-operator/pkg/apis/push/v1alpha1"
+	push "github.com/aerogear/ups-config-operator/pkg/client/push/clientset/versioned"
 )
 
 // Provides ups clients.
 // This is to allow creation of 
 type UpsClientProvider interface {
-	getPushClient() UpsClient
+	// Returns a client for the variants of the push application. The application is read
+	// from the UPS secret or, if the secret doesn't name one, from a PushApplication.
+	getPushClient(ctx context.Context) UpsClient
+	// Returns a client to manage push applications, it doesn't need a push application
+	getAdminClient(ctx context.Context) UpsClient
+	// Outcome of the last attempt to build the push client
+	getStatus() UpsClientStatus
+	// Reads the UPS secret, returns false only if it doesn't exist
+	upsSecretExists(ctx context.Context) (bool, error)
+}
+
+// Returned when a UPS call is skipped because the push client cannot be built. Retried later,
+// e.g. once the UPS secret or a PushApplication has been created.
+var errNoPushClient = errors.New("push client cannot be built")
+
+type UpsClientStatus struct {
+	UpsSecretFound bool
+	ClientBuilt    bool
 }
 
 type UpsClientProviderImpl struct {
-	k8client         *kubernetes.Clientset
-	cachedPushClient *UpsClientImpl
+	k8client          *kubernetes.Clientset
+	pushclient        *push.Clientset
+	namespace         string
+	upsSecretName     string
+	upsRequestTimeout time.Duration
+	mutex             sync.Mutex
+	cachedPushClient  *UpsClientImpl
+	upsSecretFound    bool
 }
 
-func NewUpsClientProviderImpl(k8client *kubernetes.Clientset) *UpsClientProviderImpl {
+// The UPS URL and credentials are read from the secret upsSecretName in namespace
+func NewUpsClientProviderImpl(k8client *kubernetes.Clientset, pushclient *push.Clientset, namespace string, upsSecretName string, upsRequestTimeout time.Duration) *UpsClientProviderImpl {
 	provider := new(UpsClientProviderImpl)
 	provider.k8client = k8client;
+	provider.pushclient = pushclient
+	provider.namespace = namespace
+	provider.upsSecretName = upsSecretName
+	provider.upsRequestTimeout = upsRequestTimeout
 	return provider
 }
 
-func (p *UpsClientProviderImpl) getPushClient() UpsClient {
+// Returns nil if the push client cannot be built, e.g. because the UPS secret is missing or
+// no push application has been created yet. Building the client is retried on the next call.
+func (p *UpsClientProviderImpl) getPushClient(ctx context.Context) UpsClient {
+	client := p.getCachedClient(ctx)
+	if client == nil {
+		return nil
+	}
+
+	if client.getApplicationId() != "" {
+		return client
+	}
+
+	applicationId, err := p.findPushApplicationId(ctx)
+	if err != nil {
+		log.Printf("Error looking up the push application: %v", err.Error())
+		return nil
+	}
+	if applicationId == "" {
+		log.Printf("Secret %s doesn't contain an `applicationId` and no PushApplication has been created yet", p.upsSecretName)
+		return nil
+	}
+
+	return client.withApplicationId(applicationId)
+}
+
+// Returns nil if the client cannot be built, see getPushClient
+func (p *UpsClientProviderImpl) getAdminClient(ctx context.Context) UpsClient {
+	client := p.getCachedClient(ctx)
+	if client == nil {
+		return nil
+	}
+	return client
+}
+
+func (p *UpsClientProviderImpl) getCachedClient(ctx context.Context) *UpsClientImpl {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	if p.cachedPushClient == nil {
-		client, err := createPushClient(p.k8client)
+		upsSecret := &v1.Secret{}
+		err := p.k8client.CoreV1().RESTClient().Get().
+			Context(ctx).
+			Namespace(p.namespace).
+			Resource("secrets").
+			Name(p.upsSecretName).
+			Do().
+			Into(upsSecret)
+		p.upsSecretFound = err == nil
+
+		if err != nil {
+			log.Printf("Error reading the UPS secret %s: %v", p.upsSecretName, contextError(ctx, err).Error())
+			return nil
+		}
+
+		client, err := createPushClient(upsSecret, p.upsSecretName, p.upsRequestTimeout)
 
 		if err != nil {
 			log.Printf("Error creating push client: %v", err.Error())
+			return nil
 		}
 
 		p.cachedPushClient = client
@@ -39,21 +131,103 @@ func (p *UpsClientProviderImpl) getPushClient() UpsClient {
 	return p.cachedPushClient
 }
 
-func createPushClient(k8client *kubernetes.Clientset) (*UpsClientImpl, error) {
-	upsSecret, err := k8client.CoreV1().Secrets(os.Getenv(constants.EnvVarKeyNamespace)).Get(constants.UpsSecretName, metav1.GetOptions{})
+// Returns the ID of the first PushApplication in the namespace that has been created in UPS,
+// or an empty string if there is none
+func (p *UpsClientProviderImpl) findPushApplicationId(ctx context.Context) (string, error) {
+	applications := &pushv1alpha1.PushApplicationList{}
+	err := p.pushclient.PushV1alpha1().RESTClient().Get().
+		Context(ctx).
+		Namespace(p.namespace).
+		Resource("pushapplications").
+		Do().
+		Into(applications)
+	if err != nil {
+		return "", contextError(ctx, err)
+	}
 
+	sort.Slice(applications.Items, func(i, j int) bool {
+		return applications.Items[i].Name < applications.Items[j].Name
+	})
+	for _, application := range applications.Items {
+		if application.Status.ApplicationId != "" {
+			return application.Status.ApplicationId, nil
+		}
+	}
+	return "", nil
+}
+
+func (p *UpsClientProviderImpl) upsSecretExists(ctx context.Context) (bool, error) {
+	err := p.k8client.CoreV1().RESTClient().Get().
+		Context(ctx).
+		Namespace(p.namespace).
+		Resource("secrets").
+		Name(p.upsSecretName).
+		Do().
+		Error()
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, contextError(ctx, err)
+	}
+	return true, nil
+}
+
+func (p *UpsClientProviderImpl) getStatus() UpsClientStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return UpsClientStatus{UpsSecretFound: p.upsSecretFound, ClientBuilt: p.cachedPushClient != nil}
+}
+
+// Builds a push client from the URL and credentials in the UPS secret
+func createPushClient(upsSecret *v1.Secret, upsSecretName string, upsRequestTimeout time.Duration) (*UpsClientImpl, error) {
+	upsBaseURL, err := parseUpsUrl(string(upsSecret.Data[constants.UpsSecretDataUrlKey]))
+	if err != nil {
+		return &UpsClientImpl{}, errors.Wrapf(err, "invalid `%s` in secret %s", constants.UpsSecretDataUrlKey, upsSecretName)
+	}
+
+	// The REST API is usually served below the admin console, but it can be
+	// exposed through a different route
+	upsRestURL := upsBaseURL + "/rest"
+	if rawRestURL, ok := upsSecret.Data[constants.UpsSecretDataRestUrlKey]; ok && len(rawRestURL) > 0 {
+		upsRestURL, err = parseUpsUrl(string(rawRestURL))
+		if err != nil {
+			return &UpsClientImpl{}, errors.Wrapf(err, "invalid `%s` in secret %s", constants.UpsSecretDataRestUrlKey, upsSecretName)
+		}
+	}
+
+	authenticator, err := newUpsAuthenticator(upsSecret.Data)
 	if err != nil {
-		return &UpsClientImpl{}, err
+		return &UpsClientImpl{}, errors.Wrapf(err, "invalid UPS credentials in secret %s", upsSecretName)
 	}
 
-	upsBaseURL := string(upsSecret.Data[constants.UpsSecretDataUrlKey])
 	serviceInstanceId := upsSecret.Labels[constants.UpsSecretLabelServiceInstanceIdKey]
 
 	config := &PushApplication{
-		ApplicationId: string(upsSecret.Data["applicationId"]),
+		ApplicationId: string(upsSecret.Data[constants.UpsSecretDataApplicationIdKey]),
 	}
 
-	pushClient := NewUpsClientImpl(config, serviceInstanceId, upsBaseURL)
+	pushClient := NewUpsClientImpl(config, serviceInstanceId, upsBaseURL, upsRestURL, authenticator, upsRequestTimeout)
 
 	return pushClient, nil
 }
+
+// Validates an UPS URL and strips any trailing slashes so that paths can be appended.
+// Path prefixes like `https://example.com/ups` are kept.
+func parseUpsUrl(rawUrl string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", errors.Errorf("unsupported scheme `%s`, expected http or https", parsed.Scheme)
+	}
+
+	if parsed.Host == "" {
+		return "", errors.New("missing host")
+	}
+
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
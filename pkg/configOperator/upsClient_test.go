@@ -0,0 +1,311 @@
+package configOperator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUpsClientImpl_usesRestUrlWithPathPrefix(t *testing.T) {
+	var requestedPaths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPaths = append(requestedPaths, r.URL.Path)
+		w.Write([]byte(`{"name":"myPushApp"}`))
+	}))
+	defer server.Close()
+
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "http://console.example.org", server.URL+"/prefix/rest", nil, time.Second)
+
+	name, err := client.getPushApplicationName(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if name != "myPushApp" {
+		t.Errorf("expected push application name `myPushApp` but got `%s`", name)
+	}
+
+	if len(requestedPaths) != 1 || requestedPaths[0] != "/prefix/rest/applications/myAppId" {
+		t.Errorf("unexpected requests to UPS: %v", requestedPaths)
+	}
+}
+
+func TestUpsClientImpl_updatesVariantsWithPut(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "", server.URL+"/rest", nil, time.Second)
+
+	android, err := client.updateAndroidVariant(context.Background(), &AndroidVariant{GoogleKey: "myNewKey", Variant: Variant{VariantID: "myAndroidId"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if android.VariantID != "myAndroidId" || android.GoogleKey != "myNewKey" {
+		t.Errorf("unexpected android variant: %+v", android)
+	}
+
+	ios, err := client.updateIOSVariant(context.Background(), &IOSVariant{Certificate: []byte("bXlDZXJ0"), Variant: Variant{VariantID: "myIOSId"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ios.VariantID != "myIOSId" {
+		t.Errorf("unexpected iOS variant: %+v", ios)
+	}
+
+	expected := []string{
+		"PUT /rest/applications/myAppId/android/myAndroidId",
+		"PUT /rest/applications/myAppId/ios/myIOSId",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v but got %v", expected, requests)
+	}
+}
+
+func TestUpsClientImpl_managesPushApplications(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/rest/applications/myNewAppId/android":
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"pushApplicationID":"myNewAppId","name":"myApp","masterSecret":"myMasterSecret"}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"pushApplicationID":"myNewAppId","name":"myApp","description":"myDescription"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	// the application of the client doesn't matter
+	client := NewUpsClientImpl(&PushApplication{}, "", "", server.URL+"/rest", nil, time.Second)
+
+	created, err := client.createApplication(context.Background(), &PushApplication{Name: "myApp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.ApplicationId != "myNewAppId" || created.MasterSecret != "myMasterSecret" {
+		t.Errorf("unexpected push application: %+v", created)
+	}
+
+	application, err := client.getApplication(context.Background(), "myNewAppId")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if application.Description != "myDescription" {
+		t.Errorf("unexpected push application: %+v", application)
+	}
+
+	updated, err := client.updateApplication(context.Background(), &PushApplication{ApplicationId: "myNewAppId", Name: "myRenamedApp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Name != "myRenamedApp" {
+		t.Errorf("unexpected push application: %+v", updated)
+	}
+
+	err = client.deleteApplication(context.Background(), "myNewAppId")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// variants are created in the application the client has been built for
+	_, err = client.withApplicationId("myNewAppId").getVariantsForPlatform(context.Background(), "android")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"POST /rest/applications",
+		"GET /rest/applications/myNewAppId",
+		"PUT /rest/applications/myNewAppId",
+		"DELETE /rest/applications/myNewAppId",
+		"GET /rest/applications/myNewAppId/android",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v but got %v", expected, requests)
+	}
+}
+
+func TestParseUpsUrl(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected string
+		valid    bool
+	}{
+		{"http://localhost:8080", "http://localhost:8080", true},
+		{"https://ups.example.org/prefix/", "https://ups.example.org/prefix", true},
+		{" https://ups.example.org/rest// ", "https://ups.example.org/rest", true},
+		{"ups.example.org", "", false},
+		{"ftp://ups.example.org", "", false},
+		{"", "", false},
+	}
+
+	for _, c := range cases {
+		result, err := parseUpsUrl(c.raw)
+		if c.valid && err != nil {
+			t.Errorf("expected `%s` to be valid but got error: %v", c.raw, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("expected `%s` to be invalid", c.raw)
+		}
+		if result != c.expected {
+			t.Errorf("expected `%s` to be parsed as `%s` but got `%s`", c.raw, c.expected, result)
+		}
+	}
+}
+
+func TestUpsClientImpl_returnsTypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/applications/myAppId/android":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"googleKey":"may not be null"}`))
+		case "/rest/applications/myAppId/ios":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "", server.URL+"/rest", nil, time.Second)
+	client.sleep = func(context.Context, time.Duration) error { return nil }
+
+	_, err := client.createAndroidVariant(context.Background(), &AndroidVariant{})
+	if !IsUpsValidationFailed(err) {
+		t.Errorf("expected a validation error but got %v", err)
+	}
+	if upsError, ok := err.(*UpsError); !ok || upsError.ValidationErrors["googleKey"] != "may not be null" {
+		t.Errorf("expected the validation errors of UPS to be included but got %v", err)
+	}
+
+	err = client.deleteVariant(context.Background(), "android", "unknownVariantId")
+	if !IsUpsNotFound(err) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+
+	// the iOS error must not be swallowed by the android request
+	_, err = client.getVariants(context.Background())
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected an unavailable error but got %v", err)
+	}
+
+	server.Close()
+	_, err = client.getPushApplicationName(context.Background())
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected an unavailable error when UPS cannot be reached but got %v", err)
+	}
+}
+
+func TestUpsClientImpl_retriesIdempotentRequests(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method]++
+		if requests[r.Method] < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var backoffs []time.Duration
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "", server.URL+"/rest", nil, time.Second)
+	client.sleep = func(ctx context.Context, backoff time.Duration) error {
+		backoffs = append(backoffs, backoff)
+		return nil
+	}
+
+	_, err := client.getVariantsForPlatform(context.Background(), "android")
+	if err != nil {
+		t.Errorf("expected the request to succeed after retrying but got %v", err)
+	}
+	if requests[http.MethodGet] != 3 || len(backoffs) != 2 {
+		t.Errorf("expected 3 attempts with 2 backoffs but got %d attempts and backoffs %v", requests[http.MethodGet], backoffs)
+	}
+
+	// creating a variant is not idempotent and must not be retried
+	_, err = client.createAndroidVariant(context.Background(), &AndroidVariant{})
+	if !IsUpsUnavailable(err) || requests[http.MethodPost] != 1 {
+		t.Errorf("expected a single failed attempt but got %d attempts (%v)", requests[http.MethodPost], err)
+	}
+}
+
+func TestUpsClientImpl_stopsCallingUPSWhileCircuitBreakerIsOpen(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "", server.URL+"/rest", nil, time.Second)
+	client.sleep = func(context.Context, time.Duration) error { return nil }
+	client.breaker = NewCircuitBreaker(2, time.Minute)
+	client.breaker.now = func() time.Time { return now }
+
+	_, err := client.getVariants(context.Background())
+	if err == nil || client.isAvailable() || requests != 2 {
+		t.Errorf("expected the breaker to open after 2 failed requests, got %d requests (%v)", requests, err)
+	}
+
+	_, err = client.getVariants(context.Background())
+	if err != errUpsCircuitOpen || requests != 2 {
+		t.Errorf("expected no request while the breaker is open, got %d requests (%v)", requests, err)
+	}
+
+	// after the timeout a single trial request is sent
+	now = now.Add(time.Minute)
+	if !client.isAvailable() {
+		t.Error("expected the breaker to be half-open after the timeout")
+	}
+
+	client.getVariants(context.Background())
+	if requests != 3 || client.breaker.getState() != CircuitBreakerOpen {
+		t.Errorf("expected a single failed trial request to reopen the breaker, got %d requests", requests)
+	}
+}
+
+func TestUpsClientImpl_timesOutAndCancelsRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewUpsClientImpl(&PushApplication{ApplicationId: "myAppId"}, "", "", server.URL+"/rest", nil, 50*time.Millisecond)
+	client.retryPolicy.MaxAttempts = 1
+
+	_, err := client.getPushApplicationName(context.Background())
+	if !IsUpsUnavailable(err) {
+		t.Errorf("expected an unavailable error after the request timeout but got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	client.requestTimeout = time.Minute
+	start := time.Now()
+	_, err = client.getPushApplicationName(ctx)
+	if err != context.Canceled {
+		t.Errorf("expected the request to be cancelled but got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("expected the cancelled request to return promptly")
+	}
+}
@@ -0,0 +1,132 @@
+package configOperator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type UpsErrorReason string
+
+const (
+	UpsErrorReasonNotFound         UpsErrorReason = "NotFound"
+	UpsErrorReasonConflict         UpsErrorReason = "Conflict"
+	UpsErrorReasonValidationFailed UpsErrorReason = "ValidationFailed"
+	UpsErrorReasonUnauthorized     UpsErrorReason = "Unauthorized"
+	UpsErrorReasonUnavailable      UpsErrorReason = "Unavailable"
+	UpsErrorReasonUnexpected       UpsErrorReason = "Unexpected"
+)
+
+// Error returned by the UPS client. The reason allows callers to decide how to react,
+// e.g. a variant that is not found does not have to be deleted again.
+type UpsError struct {
+	Reason     UpsErrorReason
+	StatusCode int
+	// Description of the error sent by UPS, if any
+	Message string
+	// Field validation errors sent by UPS with a `400 Bad Request`
+	ValidationErrors map[string]string
+	cause            error
+}
+
+func (err *UpsError) Error() string {
+	message := fmt.Sprintf("UPS request failed (%s", err.Reason)
+	if err.StatusCode != 0 {
+		message = fmt.Sprintf("%s, status code %d", message, err.StatusCode)
+	}
+	message = message + ")"
+
+	if len(err.ValidationErrors) > 0 {
+		var fields []string
+		for field, reason := range err.ValidationErrors {
+			fields = append(fields, fmt.Sprintf("%s: %s", field, reason))
+		}
+		sort.Strings(fields)
+		message = fmt.Sprintf("%s: %s", message, strings.Join(fields, ", "))
+	} else if err.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, err.Message)
+	}
+
+	if err.cause != nil {
+		message = fmt.Sprintf("%s: %s", message, err.cause.Error())
+	}
+
+	return message
+}
+
+// Returned without sending a request while the circuit breaker is open
+var errUpsCircuitOpen = &UpsError{Reason: UpsErrorReasonUnavailable, Message: "circuit breaker is open, UPS is considered unavailable"}
+
+// Wraps errors that occur before UPS sent a response, e.g. connection refused
+func newUpsUnavailableError(cause error) *UpsError {
+	return &UpsError{Reason: UpsErrorReasonUnavailable, cause: cause}
+}
+
+// Wraps errors that occur while obtaining credentials for a request
+func newUpsUnauthorizedError(cause error) *UpsError {
+	return &UpsError{Reason: UpsErrorReasonUnauthorized, cause: cause}
+}
+
+// Builds an error from an unsuccessful UPS response
+func newUpsResponseError(statusCode int, body []byte) *UpsError {
+	err := &UpsError{StatusCode: statusCode}
+
+	switch {
+	case statusCode == http.StatusNotFound:
+		err.Reason = UpsErrorReasonNotFound
+	case statusCode == http.StatusConflict:
+		err.Reason = UpsErrorReasonConflict
+	case statusCode == http.StatusBadRequest:
+		err.Reason = UpsErrorReasonValidationFailed
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		err.Reason = UpsErrorReasonUnauthorized
+	case statusCode >= 500:
+		err.Reason = UpsErrorReasonUnavailable
+	default:
+		err.Reason = UpsErrorReasonUnexpected
+	}
+
+	// UPS reports bean validation failures as a map of field names to messages
+	validationErrors := map[string]string{}
+	if json.Unmarshal(body, &validationErrors) == nil && len(validationErrors) > 0 {
+		err.ValidationErrors = validationErrors
+	} else {
+		err.Message = strings.TrimSpace(string(body))
+	}
+
+	return err
+}
+
+func hasUpsErrorReason(err error, reason UpsErrorReason) bool {
+	upsError, ok := errors.Cause(err).(*UpsError)
+	return ok && upsError.Reason == reason
+}
+
+func IsUpsNotFound(err error) bool {
+	return hasUpsErrorReason(err, UpsErrorReasonNotFound)
+}
+
+func IsUpsConflict(err error) bool {
+	return hasUpsErrorReason(err, UpsErrorReasonConflict)
+}
+
+func IsUpsValidationFailed(err error) bool {
+	return hasUpsErrorReason(err, UpsErrorReasonValidationFailed)
+}
+
+func IsUpsUnauthorized(err error) bool {
+	return hasUpsErrorReason(err, UpsErrorReasonUnauthorized)
+}
+
+func IsUpsUnavailable(err error) bool {
+	return hasUpsErrorReason(err, UpsErrorReasonUnavailable)
+}
+
+// Errors that won't go away when the request is repeated, UPS rejected the request itself
+func IsUpsPermanent(err error) bool {
+	return IsUpsValidationFailed(err) || IsUpsConflict(err) || IsUpsUnauthorized(err)
+}
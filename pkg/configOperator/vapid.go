@@ -0,0 +1,66 @@
+package configOperator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// Length of an uncompressed P-256 public key and of the private key scalar
+const (
+	vapidPublicKeyLength  = 65
+	vapidPrivateKeyLength = 32
+)
+
+// Generates a VAPID key pair (RFC 8292). Both keys are URL safe base64 encoded without padding,
+// the public key as an uncompressed P-256 point, which is the format browsers and UPS expect.
+func generateVapidKeys() (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot generate VAPID keys")
+	}
+
+	publicKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y)
+
+	// The scalar can be shorter than 32 bytes, pad it with leading zeros
+	privateKey := make([]byte, vapidPrivateKeyLength)
+	d := key.D.Bytes()
+	copy(privateKey[vapidPrivateKeyLength-len(d):], d)
+
+	return encodeVapidKey(publicKey), encodeVapidKey(privateKey), nil
+}
+
+// Checks that supplied VAPID keys have the format of generateVapidKeys
+func validateVapidKeys(publicKey string, privateKey string) error {
+	decodedPublicKey, err := decodeVapidKey(publicKey)
+	if err != nil || len(decodedPublicKey) != vapidPublicKeyLength {
+		return errors.New("the VAPID public key must be an uncompressed P-256 point in URL safe base64")
+	}
+
+	x, _ := elliptic.Unmarshal(elliptic.P256(), decodedPublicKey)
+	if x == nil {
+		return errors.New("the VAPID public key is not a point on the P-256 curve")
+	}
+
+	decodedPrivateKey, err := decodeVapidKey(privateKey)
+	if err != nil || len(decodedPrivateKey) != vapidPrivateKeyLength {
+		return errors.New("the VAPID private key must be a 32 byte P-256 key in URL safe base64")
+	}
+
+	return nil
+}
+
+func encodeVapidKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// Accepts keys with and without padding
+func decodeVapidKey(key string) ([]byte, error) {
+	for len(key)%4 != 0 {
+		key += "="
+	}
+	return base64.URLEncoding.DecodeString(key)
+}
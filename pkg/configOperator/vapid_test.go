@@ -0,0 +1,48 @@
+package configOperator
+
+import (
+	"testing"
+)
+
+func TestGenerateVapidKeys(t *testing.T) {
+	publicKey, privateKey, err := generateVapidKeys()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := validateVapidKeys(publicKey, privateKey); err != nil {
+		t.Errorf("generated keys are invalid: %v", err)
+	}
+
+	decoded, _ := decodeVapidKey(publicKey)
+	if decoded[0] != 0x04 {
+		t.Errorf("expected an uncompressed public key but got prefix %x", decoded[0])
+	}
+}
+
+func TestValidateVapidKeys(t *testing.T) {
+	publicKey, privateKey, _ := generateVapidKeys()
+
+	cases := []struct {
+		publicKey  string
+		privateKey string
+		valid      bool
+	}{
+		{publicKey, privateKey, true},
+		{publicKey + "==", privateKey, false},
+		{"", privateKey, false},
+		{publicKey, "", false},
+		{encodeVapidKey(make([]byte, vapidPublicKeyLength)), privateKey, false},
+		{publicKey, "not base64!", false},
+	}
+
+	for i, c := range cases {
+		err := validateVapidKeys(c.publicKey, c.privateKey)
+		if c.valid && err != nil {
+			t.Errorf("case %d: expected keys to be valid but got error: %v", i, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("case %d: expected keys to be invalid", i)
+		}
+	}
+}
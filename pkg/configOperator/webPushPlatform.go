@@ -0,0 +1,100 @@
+package configOperator
+
+import (
+	"context"
+
+	"github.com/aerogear/ups-config-operator/pkg/constants"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// Web Push variants for progressive web apps
+type WebPushPlatform struct{}
+
+func (platform *WebPushPlatform) getName() string {
+	return "webpush"
+}
+
+func (platform *WebPushPlatform) getLabel() string {
+	return "Web Push"
+}
+
+// VAPID keys that the binding doesn't provide are generated when the credentials are stored,
+// see withVapidKeys
+func (platform *WebPushPlatform) parseBinding(secret *BindingSecret) (PlatformVariant, error) {
+	publicKey := string(secret.Data[constants.BindingDataWebPushPublicKeyKey])
+	privateKey := string(secret.Data[constants.BindingDataWebPushPrivateKeyKey])
+
+	if publicKey == "" && privateKey == "" {
+		return nil, errors.New("the credentials contain no VAPID keys")
+	}
+	if err := validateVapidKeys(publicKey, privateKey); err != nil {
+		return nil, errors.Wrap(err, "invalid VAPID keys")
+	}
+
+	return &WebPushVariant{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Alias:      string(secret.Data[constants.BindingDataWebPushSubjectKey]),
+	}, nil
+}
+
+// Returns the credentials of a binding with VAPID keys. Bindings without keys get the keys of
+// the stored credentials or, the first time, a generated pair. The keys must not change: new
+// keys break the push subscriptions of all browsers.
+func withVapidKeys(data map[string][]byte, credentials *v1.Secret) (map[string][]byte, error) {
+	if len(data[constants.BindingDataWebPushPublicKeyKey]) > 0 || len(data[constants.BindingDataWebPushPrivateKeyKey]) > 0 {
+		return data, nil
+	}
+
+	withKeys := make(map[string][]byte, len(data)+2)
+	for key, value := range data {
+		withKeys[key] = value
+	}
+
+	if credentials != nil && len(credentials.Data[constants.BindingDataWebPushPublicKeyKey]) > 0 && len(credentials.Data[constants.BindingDataWebPushPrivateKeyKey]) > 0 {
+		withKeys[constants.BindingDataWebPushPublicKeyKey] = credentials.Data[constants.BindingDataWebPushPublicKeyKey]
+		withKeys[constants.BindingDataWebPushPrivateKeyKey] = credentials.Data[constants.BindingDataWebPushPrivateKeyKey]
+		return withKeys, nil
+	}
+
+	publicKey, privateKey, err := generateVapidKeys()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate VAPID keys")
+	}
+	withKeys[constants.BindingDataWebPushPublicKeyKey] = []byte(publicKey)
+	withKeys[constants.BindingDataWebPushPrivateKeyKey] = []byte(privateKey)
+	return withKeys, nil
+}
+
+func (platform *WebPushPlatform) createVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	created, err := pushClient.createWebPushVariant(ctx, variant.(*WebPushVariant))
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (platform *WebPushPlatform) findVariant(ctx context.Context, pushClient UpsClient, name string) (*Variant, error) {
+	return findVariantByName(ctx, pushClient, name, "web_push")
+}
+
+func (platform *WebPushPlatform) listVariants(ctx context.Context, pushClient UpsClient) ([]Variant, error) {
+	return listVariantsOf(ctx, pushClient, "web_push")
+}
+
+func (platform *WebPushPlatform) getVariant(ctx context.Context, pushClient UpsClient, variantId string) (*Variant, error) {
+	return getVariantOf(ctx, pushClient, variantId, "web_push")
+}
+
+func (platform *WebPushPlatform) updateVariant(ctx context.Context, pushClient UpsClient, variant PlatformVariant) (PlatformVariant, error) {
+	updated, err := pushClient.updateWebPushVariant(ctx, variant.(*WebPushVariant))
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (platform *WebPushPlatform) deleteVariant(ctx context.Context, pushClient UpsClient, secret *BindingSecret, variantId string) error {
+	return pushClient.deleteVariant(ctx, "web_push", variantId)
+}
@@ -1,17 +1,131 @@
 package constants
 
 const (
-	EnvVarKeyNamespace = "NAMESPACE"
+	// Environment variables that set the defaults of the command line flags, see `server -help`
+	EnvVarKeyKubeconfig     = "KUBECONFIG"
+	EnvVarKeyNamespace      = "NAMESPACE"
+	EnvVarKeyUpsSecretName  = "UPS_SECRET_NAME"
+	EnvVarKeyLogLevel       = "LOG_LEVEL"
+	EnvVarKeyMetricsAddress = "METRICS_ADDRESS"
+	EnvVarKeyDryRun         = "DRY_RUN"
 
-	K8SecretEventTypeAdded   = "ADDED"
-	K8SecretEventTypeDeleted = "DELETED"
+	// One of `report`, `adopt` or `delete`
+	EnvVarKeyOrphanedVariantPolicy = "ORPHANED_VARIANT_POLICY"
 
-	// time in seconds
+	// Numbers
+	EnvVarKeyDriftMissedPolls  = "DRIFT_MISSED_POLLS"
+	EnvVarKeyDriftMaxDeletions = "DRIFT_MAX_DELETIONS"
+
+	// Durations like `10s`, see time.ParseDuration
+	EnvVarKeyUpsPollingInterval          = "UPS_POLLING_INTERVAL"
+	EnvVarKeyUpsRequestTimeout           = "UPS_REQUEST_TIMEOUT"
+	EnvVarKeyKubeRequestTimeout          = "KUBE_REQUEST_TIMEOUT"
+	EnvVarKeyShutdownGracePeriod         = "SHUTDOWN_GRACE_PERIOD"
+	EnvVarKeyLivenessWindow              = "LIVENESS_WINDOW"
+	EnvVarKeyLeaderElectionLeaseDuration = "LEADER_ELECTION_LEASE_DURATION"
+	EnvVarKeyLeaderElectionRenewDeadline = "LEADER_ELECTION_RENEW_DEADLINE"
+	EnvVarKeyLeaderElectionRetryPeriod   = "LEADER_ELECTION_RETRY_PERIOD"
+
+	// Default address of the /metrics, /healthz and /readyz endpoints
+	MetricsAddress = ":8080"
+
+	// Default time in seconds in which the secret watch and the UPS poll have to make progress
+	// to pass the liveness check
+	LivenessWindow = 180
+
+	// Default interval in which UPS is checked for deleted variants (time in seconds)
 	UPSPollingInterval = 10
 
+	// By default a variant that cannot be looked up directly counts as deleted once it has been
+	// missing in this many polls in a row, and a poll deletes at most this many service bindings
+	DriftMissedPolls  = 3
+	DriftMaxDeletions = 5
+
+	// Changes recorded in dry-run mode that are served on `/dry-run`, older ones are dropped
+	DryRunActionsLimit = 1000
+
+	// time in seconds after which the informers deliver all objects again
+	InformerResyncPeriod = 300
+
+	// Label selectors of the secrets handled by the operator
+	BindingSecretSelector     = SecretTypeLabelKey + "=" + BindingSecretTypeMobile
+	ConfigSecretSelector      = "serviceName=ups"
+	CredentialsSecretSelector = PushVariantLabelKey
+
+	// Default timeouts of a single request (time in seconds)
+	UPSRequestTimeout  = 10
+	KubeRequestTimeout = 30
+
+	// Default time in seconds that in-flight work may take after a termination signal. Should
+	// be shorter than the terminationGracePeriodSeconds of the pod, which defaults to 30.
+	ShutdownGracePeriod = 20
+
+	// Type of the entry in MobileClient.Status.Services that holds the push config, and the
+	// version of its config format
+	PushServiceType    = "push"
+	PushServiceVersion = "1"
+
+	// Source of the Kubernetes events recorded by the operator
+	EventSourceComponent = "ups-config-operator"
+
+	// Keeps config secrets until the operator has deleted their variants from UPS
+	ConfigSecretFinalizer = "push.aerogear.org/delete-variants"
+
+	// Keeps PushApplications until the operator has deleted their application from UPS
+	PushApplicationFinalizer = "push.aerogear.org/delete-application"
+
+	// Names the PushVariant of a credentials secret, so that changed credentials are synced
+	PushVariantLabelKey = "push.aerogear.org/push-variant"
+
+	// Service bindings with this annotation set to `true` are not deleted when their variant
+	// has been deleted in UPS
+	ProtectedBindingAnnotation = "push.aerogear.org/protected"
+
+	// Config secrets with this annotation set to `true` are released by their finalizer without
+	// deleting their variants from UPS, e.g. because UPS is gone
+	SkipCleanupAnnotation = "push.aerogear.org/skip-cleanup"
+
+	// Name of the ConfigMap that holds the leader election lock
+	LeaderElectionLockName = "ups-config-operator-leader"
+
+	// Default leader election durations (time in seconds). A standby replica takes over
+	// at the latest LeaderElectionLeaseDuration after the leader stopped renewing the lock.
+	LeaderElectionLeaseDuration = 15
+	LeaderElectionRenewDeadline = 10
+	LeaderElectionRetryPeriod   = 2
+
+	// Idempotent UPS requests are retried with an exponential backoff (time in milliseconds)
+	UPSRequestMaxAttempts  = 4
+	UPSRetryInitialBackoff = 500
+	UPSRetryMaxBackoff     = 5000
+
+	// UPS is considered unavailable after this many consecutive failures
+	UPSCircuitBreakerFailureThreshold = 5
+	// time in seconds before a trial request is sent to an unavailable UPS
+	UPSCircuitBreakerOpenTimeout = 30
+
 	UpsSecretName = "unified-push-server"
 
-	UpsSecretDataUrlKey                = "uri"
+	// URL of the UPS admin console, used in annotations and client configs
+	UpsSecretDataUrlKey = "uri"
+	// URL of the UPS REST API. Optional, defaults to `<uri>/rest`
+	UpsSecretDataRestUrlKey = "restUri"
+	// ID of the push application the variants are created in. Optional if a PushApplication
+	// has been created
+	UpsSecretDataApplicationIdKey = "applicationId"
+
+	// Keys of the secret generated for a PushApplication
+	PushApplicationSecretApplicationIdKey = "applicationId"
+	PushApplicationSecretMasterSecretKey  = "masterSecret"
+
+	// Optional credentials for UPS instances with authentication enabled
+	UpsSecretDataUsernameKey             = "username"
+	UpsSecretDataPasswordKey             = "password"
+	UpsSecretDataKeycloakUrlKey          = "keycloakUrl"
+	UpsSecretDataKeycloakRealmKey        = "keycloakRealm"
+	UpsSecretDataKeycloakClientIdKey     = "keycloakClientId"
+	UpsSecretDataKeycloakClientSecretKey = "keycloakClientSecret"
+
 	UpsSecretLabelServiceInstanceIdKey = "serviceInstanceID"
 
 	SecretTypeLabelKey = "secretType"
@@ -31,6 +145,17 @@ const (
 	BindingDataIOSPassPhraseKey   = "passphrase"
 	BindingDataIOSIsProductionKey = "isProduction"
 
+	// Token based APNs authentication, used instead of the certificate when a private key is present
+	BindingDataIOSPrivateKeyKey = "privateKey"
+	BindingDataIOSKeyIdKey      = "keyId"
+	BindingDataIOSTeamIdKey     = "teamId"
+	BindingDataIOSBundleIdKey   = "bundleId"
+
+	// VAPID keys are generated when the binding does not provide them
+	BindingDataWebPushPublicKeyKey  = "vapidPublicKey"
+	BindingDataWebPushPrivateKeyKey = "vapidPrivateKey"
+	BindingDataWebPushSubjectKey    = "vapidSubject"
+
 	PushAppAnnotationNameFormat = "org.aerogear.binding.%s/push-application"
 	UpsUrlAnnotationNameFormat = "org.aerogear.binding.%s/ups-url"
 	ExtVariantsAnnotationNameFormat = "org.aerogear.binding-ext.%s/variants"